@@ -1,9 +1,71 @@
 package chanim
 
+// Variation is a frame series that an animation plays with the given weight
+type Variation struct {
+	FrameSeriesName string
+	// Weight is the relative probability to play the series
+	Weight int
+}
+
 // Animation description
 type Animation struct {
 	Name            string
 	FrameSeriesName string
+
+	// Variations are the frame series played instead of FrameSeriesName.
+	// The animator picks a random variation at the animation start
+	// and at each loop boundary. While a change looks for a transition frame,
+	// only the variations with transition frames to the next animation are picked.
+	Variations []Variation
+
+	// FrameRate is the frame rate of the animation.
+	// If it is zero, the animator frame rate is used.
+	FrameRate int
+
+	// LoopMode is the order in which the animation frames are played
+	LoopMode LoopMode
+	// RandomStart makes the animation start from a random frame,
+	// so characters sharing the same series are not in lockstep
+	RandomStart bool
+
+	// LoopCount is the number of times the animation is played before the animator
+	// changes to the NextAnimationName animation.
+	// If it is zero, the animation is looped forever.
+	LoopCount int
+	// NextAnimationName is the name of the animation to change to after LoopCount loops
+	NextAnimationName string
+
+	// InterruptFrameSeriesName is the name of the frame series played before the animation
+	// when it is entered by a forced change. It may be empty.
+	InterruptFrameSeriesName string
+
+	// Transitions are the transitions available from any frame of the animation.
+	// The transitions of the shown frame override them.
+	Transitions []Transition
+}
+
+// GetFrameSeriesNames gets the names of all frame series played by the animation
+func (animation *Animation) GetFrameSeriesNames() []string {
+	if len(animation.Variations) == 0 {
+		return []string{animation.FrameSeriesName}
+	}
+
+	frameSeriesNames := make([]string, 0, len(animation.Variations))
+	for _, variation := range animation.Variations {
+		frameSeriesNames = append(frameSeriesNames, variation.FrameSeriesName)
+	}
+	return frameSeriesNames
+}
+
+// GetSeriesForTransition returns the name of the series of frames that should
+// be played to move from any frame of the animation to the destAnimation.
+func (animation *Animation) GetSeriesForTransition(destAnimationName string) (string, bool) {
+	for _, transition := range animation.Transitions {
+		if transition.DestAnimationName == destAnimationName {
+			return transition.FrameSeriesName, true
+		}
+	}
+	return "", false
 }
 
 // Animations is animation set
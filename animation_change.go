@@ -0,0 +1,97 @@
+package chanim
+
+import "sync"
+
+// AnimationChange is a pending animation change
+type AnimationChange struct {
+	animationName string
+	done          chan struct{}
+	err           error
+
+	completed    chan struct{}
+	completeOnce sync.Once
+
+	mutex sync.Mutex
+	route []string
+
+	// The index of the next animation in the route, guarded by the animator mutex
+	hop int
+}
+
+func newAnimationChange(animationName string) *AnimationChange {
+	return &AnimationChange{
+		animationName: animationName,
+		done:          make(chan struct{}),
+		completed:     make(chan struct{}),
+	}
+}
+
+// AnimationName gets the name of the destination animation
+func (change *AnimationChange) AnimationName() string {
+	return change.animationName
+}
+
+// Route gets the animations the change goes through. It starts with the animation
+// that was current when the change started and ends with the destination animation.
+// Route returns nil until the change is started.
+func (change *AnimationChange) Route() []string {
+	change.mutex.Lock()
+	defer change.mutex.Unlock()
+	return change.route
+}
+
+// Done returns a channel that is closed when the change is finished
+func (change *AnimationChange) Done() <-chan struct{} {
+	return change.done
+}
+
+// Completed returns a channel that is closed when the destination animation
+// has played all its loops (see Animation.LoopCount). For animations looped forever
+// the channel is closed when the animation stops playing. For failed changes
+// the channel is closed when the change is finished.
+func (change *AnimationChange) Completed() <-chan struct{} {
+	return change.completed
+}
+
+// Err returns the change error.
+// It must be called after the channel returned by Done is closed.
+func (change *AnimationChange) Err() error {
+	return change.err
+}
+
+// Wait waits for the change to finish and returns its error
+func (change *AnimationChange) Wait() error {
+	<-change.done
+	return change.err
+}
+
+func (change *AnimationChange) setRoute(route []string) {
+	change.mutex.Lock()
+	change.route = route
+	change.hop = 1
+	change.mutex.Unlock()
+}
+
+// nextAnimationName gets the name of the next animation in the route
+func (change *AnimationChange) nextAnimationName() string {
+	return change.route[change.hop]
+}
+
+// isLastHop checks whether the next animation in the route is the destination animation
+func (change *AnimationChange) isLastHop() bool {
+	return change.hop == len(change.route)-1
+}
+
+func (change *AnimationChange) finish(err error) {
+	change.err = err
+	close(change.done)
+	if err != nil {
+		change.complete()
+	}
+}
+
+func (change *AnimationChange) complete() {
+	change.completeOnce.Do(func() {
+		close(change.completed)
+	})
+}
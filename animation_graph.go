@@ -0,0 +1,152 @@
+package chanim
+
+import "container/heap"
+
+// animationGraphEdge is a transition from one animation to another.
+// The cost is the number of frames that have to be played to reach the destination
+// animation when the source animation is played from its first frame.
+// The transitions of the animation are available from its first frame.
+type animationGraphEdge struct {
+	destAnimationName string
+	cost              int
+}
+
+// animationGraph is a graph of transitions between animations
+type animationGraph struct {
+	edges map[string][]animationGraphEdge
+}
+
+func newAnimationGraph(animations Animations, allFrameSeries []FrameSeries) *animationGraph {
+	seriesLengths := make(map[string]int)
+	for _, frameSeries := range allFrameSeries {
+		seriesLengths[frameSeries.Name] = len(frameSeries.Frames)
+	}
+
+	graph := &animationGraph{
+		edges: make(map[string][]animationGraphEdge),
+	}
+	for _, animation := range animations {
+		costs := make(map[string]int)
+		addTransition := func(transition Transition, frameNum int) {
+			transitionLength := 0
+			if transition.FrameSeriesName != "" {
+				var ok bool
+				transitionLength, ok = seriesLengths[transition.FrameSeriesName]
+				if !ok {
+					return
+				}
+			}
+
+			cost := frameNum + 1 + transitionLength
+			if oldCost, ok := costs[transition.DestAnimationName]; !ok || cost < oldCost {
+				costs[transition.DestAnimationName] = cost
+			}
+		}
+
+		for _, frameSeries := range allFrameSeries {
+			if !containsString(animation.GetFrameSeriesNames(), frameSeries.Name) {
+				continue
+			}
+
+			for i, frame := range frameSeries.Frames {
+				for _, transition := range frame.Transitions {
+					addTransition(transition, i)
+				}
+			}
+		}
+
+		for _, transition := range animation.Transitions {
+			addTransition(transition, 0)
+		}
+
+		for destAnimationName, cost := range costs {
+			graph.edges[animation.Name] = append(graph.edges[animation.Name],
+				animationGraphEdge{destAnimationName, cost})
+		}
+	}
+
+	return graph
+}
+
+// findRoute finds the shortest route in frames from one animation to another.
+// The route starts with fromAnimationName and ends with toAnimationName.
+func (graph *animationGraph) findRoute(fromAnimationName string, toAnimationName string) ([]string, bool) {
+	costs := map[string]int{fromAnimationName: 0}
+	prev := make(map[string]string)
+	visited := make(map[string]bool)
+
+	queue := &routeQueue{{fromAnimationName, 0}}
+	for queue.Len() > 0 {
+		item := heap.Pop(queue).(routeQueueItem)
+		if visited[item.animationName] {
+			continue
+		}
+		visited[item.animationName] = true
+
+		if item.animationName == toAnimationName {
+			break
+		}
+
+		for _, edge := range graph.edges[item.animationName] {
+			cost := item.cost + edge.cost
+			if oldCost, ok := costs[edge.destAnimationName]; ok && oldCost <= cost {
+				continue
+			}
+			costs[edge.destAnimationName] = cost
+			prev[edge.destAnimationName] = item.animationName
+			heap.Push(queue, routeQueueItem{edge.destAnimationName, cost})
+		}
+	}
+
+	if !visited[toAnimationName] {
+		return nil, false
+	}
+
+	route := []string{toAnimationName}
+	for animationName := toAnimationName; animationName != fromAnimationName; {
+		animationName = prev[animationName]
+		route = append([]string{animationName}, route...)
+	}
+	return route, true
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+type routeQueueItem struct {
+	animationName string
+	cost          int
+}
+
+// routeQueue is a priority queue of animations ordered by the route cost
+type routeQueue []routeQueueItem
+
+func (q routeQueue) Len() int {
+	return len(q)
+}
+
+func (q routeQueue) Less(i, j int) bool {
+	return q[i].cost < q[j].cost
+}
+
+func (q routeQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+}
+
+func (q *routeQueue) Push(x interface{}) {
+	*q = append(*q, x.(routeQueueItem))
+}
+
+func (q *routeQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	*q = old[:n-1]
+	return item
+}
@@ -1,8 +1,12 @@
 package chanim
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"image"
+	"io"
+	"math/rand"
 	"sync"
 	"time"
 
@@ -24,41 +28,112 @@ const (
 
 const defaultFrameRate = 25
 
+var (
+	// ErrAnimatorStopped is the error of animation changes interrupted by stopping the animator
+	ErrAnimatorStopped = errors.New("Animator is stopped")
+	// ErrAnimationChangeRejected is the error of animation changes rejected
+	// because another change is in progress
+	ErrAnimationChangeRejected = errors.New("Animator is already making a animation change")
+	// ErrAnimationChangeSuperseded is the error of queued animation changes
+	// replaced by a later change
+	ErrAnimationChangeSuperseded = errors.New("Animation change is superseded by a later change")
+)
+
 // Animator implements character animation
 type Animator struct {
 	paintEngine    PaintEngine
+	clock          Clock
+	rand           *rand.Rand
 	animations     Animations
 	allFrameSeries []FrameSeries
+	graph          *animationGraph
 
-	frameRate int
+	// Indexes of animations and frame series by name
+	animationIndex   map[string]int
+	frameSeriesIndex map[string]int
 
-	mutex                 sync.Mutex
-	isRunning             bool
-	animationName         string
-	nextAnimationName     *string
-	animationChangedCond  *sync.Cond
-	animationChangedError error
+	frameRate         int
+	changeQueuePolicy ChangeQueuePolicy
+	clearOnStop       bool
+	errorPolicy       ErrorPolicy
+	dropPolicy        DropPolicy
+
+	mutex     sync.Mutex
+	isRunning bool
+	isClosed  bool
+	isPaused  bool
+	stepCount int
+	drawCond  *sync.Cond
+	drawDone  chan struct{}
+	// wake interrupts the sleep of the drawing goroutine
+	wake          chan struct{}
+	animationName string
+	change        *AnimationChange
+	changeQueue   []*AnimationChange
+	// Changes whose destination is the current animation
+	playingChanges []*AnimationChange
+	subscribers    []*eventSubscriber
 
 	state animationState
 
-	playedFrames []Frame
-	nextFrameNum int
-	shownFrame   *Frame
+	playedFrames    []Frame
+	playedFrameRate int
+	loopMode        LoopMode
+	nextFrameNum    int
+	frameNumStep    int
+	loopFrameCount  int
+	shownFrame      *Frame
+	loopCounter     int
+
+	// The scene which draws the frames of the animator, nil for standalone animators
+	scene         *Scene
+	layerSchedule frameSchedule
+
+	errorCount int
+	lastError  error
+
+	stats statsCollector
 
 	tryInitTransitionCounter int
 }
 
-// NewAnimator creats new Animator
-func NewAnimator(paintEngine PaintEngine, animations Animations, allFrameSeries []FrameSeries) (*Animator, error) {
+// NewAnimator creats new Animator.
+// The animations and frame series are checked by Validate, the warnings are only logged.
+func NewAnimator(paintEngine PaintEngine, animations Animations, allFrameSeries []FrameSeries,
+	options ...AnimatorOption) (*Animator, error) {
+
+	if err := Validate(animations, allFrameSeries); err != nil {
+		if validationErr, ok := err.(*ValidationError); !ok || validationErr.hasErrors() {
+			return nil, err
+		}
+		logrus.Warnf("Animator: %v\n", err)
+	}
 
 	animator := &Animator{
-		paintEngine:    paintEngine,
-		animations:     animations,
-		allFrameSeries: allFrameSeries,
-		frameRate:      defaultFrameRate,
-		state:          asPlayCurrentAnimation,
+		paintEngine:      paintEngine,
+		clock:            RealClock(),
+		rand:             rand.New(rand.NewSource(time.Now().UnixNano())),
+		animations:       append(Animations(nil), animations...),
+		allFrameSeries:   append([]FrameSeries(nil), allFrameSeries...),
+		graph:            newAnimationGraph(animations, allFrameSeries),
+		animationIndex:   make(map[string]int),
+		frameSeriesIndex: make(map[string]int),
+		frameRate:        defaultFrameRate,
+		state:            asPlayCurrentAnimation,
+	}
+	for i, animation := range animator.animations {
+		animator.animationIndex[animation.Name] = i
+	}
+	for i, frameSeries := range animator.allFrameSeries {
+		animator.frameSeriesIndex[frameSeries.Name] = i
+	}
+	animator.drawCond = sync.NewCond(&animator.mutex)
+	for _, option := range options {
+		option(animator)
+	}
+	if err := animator.checkOptions(); err != nil {
+		return nil, err
 	}
-	animator.animationChangedCond = sync.NewCond(&animator.mutex)
 	return animator, nil
 }
 
@@ -71,94 +146,648 @@ func (animator *Animator) GetAnimationNames() []string {
 	return animationNames
 }
 
+// GetFrameSeriesNames gets frame series names
+func (animator *Animator) GetFrameSeriesNames() []string {
+	frameSeriesNames := make([]string, 0, len(animator.allFrameSeries))
+	for _, frameSeries := range animator.allFrameSeries {
+		frameSeriesNames = append(frameSeriesNames, frameSeries.Name)
+	}
+	return frameSeriesNames
+}
+
+// GetAnimation gets the animation by name or nil if there is no such animation.
+// The returned animation is owned by the animator and must not be modified.
+func (animator *Animator) GetAnimation(animationName string) *Animation {
+	return animator.findAnimationByName(animationName)
+}
+
+// GetFrameSeries gets the frame series by name or nil if there is no such series.
+// The returned series is owned by the animator and must not be modified.
+func (animator *Animator) GetFrameSeries(frameSeriesName string) *FrameSeries {
+	return animator.findFrameSeriesByName(frameSeriesName)
+}
+
+// FindRoute finds the shortest route in frames between animations.
+// The route starts with fromAnimationName and ends with toAnimationName,
+// intermediate animations are played to reach the destination animation.
+func (animator *Animator) FindRoute(fromAnimationName string, toAnimationName string) ([]string, error) {
+	for _, animationName := range []string{fromAnimationName, toAnimationName} {
+		if animator.findAnimationByName(animationName) == nil {
+			return nil, fmt.Errorf("Could't find a animation named '%s'", animationName)
+		}
+	}
+
+	route, ok := animator.graph.findRoute(fromAnimationName, toAnimationName)
+	if !ok {
+		return nil, fmt.Errorf("Could't find a route from '%s' to '%s'", fromAnimationName, toAnimationName)
+	}
+	return route, nil
+}
+
+// GetFrameRate gets the animator frame rate
+func (animator *Animator) GetFrameRate() int {
+	animator.mutex.Lock()
+	defer animator.mutex.Unlock()
+	return animator.frameRate
+}
+
+// SetFrameRate sets the animator frame rate.
+// The new frame rate takes effect from the next frame.
+func (animator *Animator) SetFrameRate(frameRate int) error {
+	if frameRate <= 0 {
+		return fmt.Errorf("Invalid frame rate %v", frameRate)
+	}
+
+	animator.mutex.Lock()
+	animator.frameRate = frameRate
+	animator.mutex.Unlock()
+	return nil
+}
+
+// Subscribe registers a listener for Animator events.
+// The events are delivered in order in a separate goroutine, so the listener never
+// blocks drawing. The returned function cancels the subscription.
+func (animator *Animator) Subscribe(listener EventListener) func() {
+	subscriber := newEventSubscriber(listener)
+
+	animator.mutex.Lock()
+	animator.subscribers = append(animator.subscribers, subscriber)
+	animator.mutex.Unlock()
+
+	return func() {
+		animator.mutex.Lock()
+		defer animator.mutex.Unlock()
+
+		for i, existing := range animator.subscribers {
+			if existing == subscriber {
+				animator.subscribers = append(animator.subscribers[:i], animator.subscribers[i+1:]...)
+				subscriber.close()
+				return
+			}
+		}
+	}
+}
+
 // Start drawing
 func (animator *Animator) Start(initAnimationName string) error {
 	animator.mutex.Lock()
 	defer animator.mutex.Unlock()
 
+	if animator.isClosed {
+		return errors.New("Animator is closed")
+	}
+
 	if animator.isRunning {
 		return errors.New("Animator is already running")
 	}
 
+	if animator.drawDone != nil {
+		select {
+		case <-animator.drawDone:
+		default:
+			return errors.New("Animator is still stopping")
+		}
+	}
+
 	err := animator.setAnimation(initAnimationName)
 	if err != nil {
 		return err
 	}
+	animator.emit(Event{Type: EventAnimationStarted, AnimationName: initAnimationName})
 
 	animator.isRunning = true
-	go animator.doDraw()
+	animator.isPaused = false
+	animator.stepCount = 0
+	animator.shownFrame = nil
+	if animator.scene != nil {
+		// The frames are drawn by the scene
+		animator.layerSchedule = frameSchedule{}
+		return nil
+	}
+
+	animator.drawDone = make(chan struct{})
+	animator.wake = make(chan struct{}, 1)
+	go animator.doDraw(animator.drawDone, animator.wake)
 	return nil
 }
 
-// Stop drawing
+// Stop stops drawing and waits for the drawing goroutine to exit.
+// The pending animation changes fail with ErrAnimatorStopped.
 func (animator *Animator) Stop() {
 	animator.mutex.Lock()
-	animator.isRunning = false
+	animator.stop()
+	drawDone := animator.drawDone
 	animator.mutex.Unlock()
+
+	if drawDone != nil {
+		<-drawDone
+	}
+}
+
+// Close stops drawing and releases the paint engine if it implements io.Closer.
+// The animator can't be started after Close. Calling Close again does nothing.
+func (animator *Animator) Close() error {
+	animator.mutex.Lock()
+	isClosed := animator.isClosed
+	animator.isClosed = true
+	animator.mutex.Unlock()
+
+	if isClosed {
+		return nil
+	}
+
+	animator.Stop()
+
+	if closer, ok := animator.paintEngine.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Pause freezes the current frame on the screen
+func (animator *Animator) Pause() {
+	animator.mutex.Lock()
+	animator.isPaused = true
+	animator.stepCount = 0
+	animator.wakeUp()
+	animator.mutex.Unlock()
+}
+
+// Resume resumes the paused playback
+func (animator *Animator) Resume() {
+	animator.mutex.Lock()
+	animator.isPaused = false
+	animator.stepCount = 0
+	animator.drawCond.Broadcast()
+	animator.mutex.Unlock()
+}
+
+// Step shows the next frameCount frames of the paused animator.
+// The frames are played at the current frame rate.
+func (animator *Animator) Step(frameCount int) error {
+	animator.mutex.Lock()
+	defer animator.mutex.Unlock()
+
+	if !animator.isPaused {
+		return errors.New("Animator is not paused")
+	}
+
+	if frameCount <= 0 {
+		return fmt.Errorf("Invalid frame count %v", frameCount)
+	}
+
+	animator.stepCount += frameCount
+	animator.drawCond.Broadcast()
+	return nil
+}
+
+func (animator *Animator) stop() {
+	animator.isRunning = false
+	animator.drawCond.Broadcast()
+	animator.wakeUp()
+	for _, change := range animator.changeQueue {
+		change.finish(ErrAnimatorStopped)
+	}
+	animator.changeQueue = nil
+	if animator.change != nil {
+		animator.finishChangeAnimation(ErrAnimatorStopped)
+	}
+	animator.completePlayingChanges()
 }
 
 // ChangeAnimation changes the current animation
 func (animator *Animator) ChangeAnimation(nextAnimationName string) error {
+	return animator.ChangeAnimationContext(context.Background(), nextAnimationName)
+}
+
+// ChangeAnimationContext changes the current animation.
+// If the context is done before a transition to the next animation has been started,
+// the change is cancelled and the context error is returned. Otherwise the change
+// goes on and its result is returned. For routes through intermediate animations
+// only the first transition of the route can be cancelled.
+func (animator *Animator) ChangeAnimationContext(ctx context.Context, nextAnimationName string) error {
+	return animator.ChangeAnimationAsync(ctx, nextAnimationName).Wait()
+}
+
+// ChangeAnimationNow changes the current animation immediately without waiting
+// for a transition frame. The pending changes fail with ErrAnimationChangeSuperseded.
+// If the next animation has an interrupt frame series, it is played before the animation.
+// If the context is done before the change starts, the change fails with the context error.
+// If the context is done while the interrupt series is played, the context error is returned
+// without waiting, but the change goes on, since the current animation is already left.
+func (animator *Animator) ChangeAnimationNow(ctx context.Context, nextAnimationName string) error {
+	change := animator.ChangeAnimationNowAsync(ctx, nextAnimationName)
+	select {
+	case <-change.Done():
+		return change.Err()
+	case <-ctx.Done():
+		select {
+		case <-change.Done():
+			return change.Err()
+		default:
+			return ctx.Err()
+		}
+	}
+}
+
+// ChangeAnimationNowAsync starts changing the current animation immediately
+// and returns without waiting (see ChangeAnimationNow).
+// If the context is already done, the change fails with the context error.
+func (animator *Animator) ChangeAnimationNowAsync(ctx context.Context, nextAnimationName string) *AnimationChange {
+	change := newAnimationChange(nextAnimationName)
+	if err := ctx.Err(); err != nil {
+		change.finish(err)
+		return change
+	}
+
 	animator.mutex.Lock()
 	defer animator.mutex.Unlock()
 
 	if !animator.isRunning {
-		return errors.New("Animator is not running")
+		change.finish(errors.New("Animator is not running"))
+		return change
 	}
 
-	if animator.nextAnimationName != nil || animator.state != asPlayCurrentAnimation {
-		return errors.New("Animator is already making a animation change")
+	if animator.change == nil && animator.animationName == nextAnimationName {
+		change.finish(nil)
+		animator.playingChanges = append(animator.playingChanges, change)
+		return change
 	}
 
-	if animator.animationName == nextAnimationName {
-		return nil
+	animator.startChangeAnimationNow(change)
+	return change
+}
+
+// startChangeAnimationNow supersedes the pending changes and cuts to the destination
+// animation of the change or to its interrupt frame series
+func (animator *Animator) startChangeAnimationNow(change *AnimationChange) {
+	nextAnimation := animator.findAnimationByName(change.animationName)
+	if nextAnimation == nil {
+		change.finish(fmt.Errorf("Could't find a animation named '%s'", change.animationName))
+		return
+	}
+
+	var interruptFrameSeries *FrameSeries
+	if nextAnimation.InterruptFrameSeriesName != "" {
+		interruptFrameSeries = animator.findFrameSeriesByName(nextAnimation.InterruptFrameSeriesName)
+		if interruptFrameSeries == nil {
+			change.finish(fmt.Errorf("Could't find a series of frames named '%s'",
+				nextAnimation.InterruptFrameSeriesName))
+			return
+		}
+	}
+
+	animator.supersedeChanges()
+	change.setRoute([]string{animator.animationName, change.animationName})
+	animator.change = change
+	animator.wakeUp()
+	if interruptFrameSeries == nil {
+		animator.finishChangeAnimation(nil)
+		return
 	}
 
-	animator.nextAnimationName = &nextAnimationName
+	animator.startTransition(interruptFrameSeries.Name, interruptFrameSeries)
+}
+
+// supersedeChanges fails the queued changes and the change in progress
+// with ErrAnimationChangeSuperseded
+func (animator *Animator) supersedeChanges() {
+	for _, queuedChange := range animator.changeQueue {
+		queuedChange.finish(ErrAnimationChangeSuperseded)
+	}
+	animator.changeQueue = nil
+
+	change := animator.change
+	if change == nil {
+		return
+	}
+
+	animator.change = nil
+	animator.emit(Event{
+		Type:              EventChangeFailed,
+		AnimationName:     animator.animationName,
+		DestAnimationName: change.animationName,
+		Err:               ErrAnimationChangeSuperseded,
+	})
+	change.finish(ErrAnimationChangeSuperseded)
+}
+
+// ChangeAnimationAsync starts changing the current animation and returns without waiting.
+// If another change is in progress, the new change is handled according to the change queue policy.
+// The change is cancelled if the context is done before the first transition
+// of the route has been started.
+func (animator *Animator) ChangeAnimationAsync(ctx context.Context, nextAnimationName string) *AnimationChange {
+	change := newAnimationChange(nextAnimationName)
+
+	animator.mutex.Lock()
+	defer animator.mutex.Unlock()
+
+	if !animator.isRunning {
+		change.finish(errors.New("Animator is not running"))
+		return change
+	}
+
+	if animator.change != nil {
+		switch animator.changeQueuePolicy {
+		case QueueChanges:
+			animator.changeQueue = append(animator.changeQueue, change)
+		case CoalesceChanges:
+			for _, queuedChange := range animator.changeQueue {
+				queuedChange.finish(ErrAnimationChangeSuperseded)
+			}
+			animator.changeQueue = []*AnimationChange{change}
+		default:
+			change.finish(ErrAnimationChangeRejected)
+			return change
+		}
+	} else {
+		animator.startChangeAnimation(change)
+	}
+
+	if ctx.Done() != nil {
+		go animator.watchChangeContext(ctx, change)
+	}
+	return change
+}
+
+func (animator *Animator) watchChangeContext(ctx context.Context, change *AnimationChange) {
+	select {
+	case <-change.Done():
+	case <-ctx.Done():
+		animator.cancelChangeAnimation(change, ctx.Err())
+	}
+}
+
+func (animator *Animator) cancelChangeAnimation(change *AnimationChange, err error) {
+	animator.mutex.Lock()
+	defer animator.mutex.Unlock()
+
+	for i, queuedChange := range animator.changeQueue {
+		if queuedChange == change {
+			animator.changeQueue = append(animator.changeQueue[:i], animator.changeQueue[i+1:]...)
+			change.finish(err)
+			return
+		}
+	}
+
+	if animator.change != change || animator.state == asTransitionToNextAnimation || change.hop > 1 {
+		// The change is finished, the transition frames are already playing
+		// or the animator has left the source animation
+		return
+	}
+
+	animator.change = nil
+	animator.state = asPlayCurrentAnimation
+	animator.emit(Event{
+		Type:              EventChangeFailed,
+		AnimationName:     animator.animationName,
+		DestAnimationName: change.animationName,
+		Err:               err,
+	})
+	change.finish(err)
+	animator.startNextChangeAnimation()
+}
+
+func (animator *Animator) startChangeAnimation(change *AnimationChange) {
+	if animator.animationName == change.animationName {
+		change.finish(nil)
+		animator.playingChanges = append(animator.playingChanges, change)
+		return
+	}
+
+	route, err := animator.FindRoute(animator.animationName, change.animationName)
+	if err != nil {
+		animator.emit(Event{
+			Type:              EventChangeFailed,
+			AnimationName:     animator.animationName,
+			DestAnimationName: change.animationName,
+			Err:               err,
+		})
+		change.finish(err)
+		return
+	}
+
+	change.setRoute(route)
+	animator.change = change
 	animator.state = asInitChangeAnimation
-	animator.animationChangedCond.Wait()
-	animator.nextAnimationName = nil
+}
+
+func (animator *Animator) startNextChangeAnimation() {
+	for animator.change == nil && len(animator.changeQueue) > 0 {
+		change := animator.changeQueue[0]
+		animator.changeQueue = animator.changeQueue[1:]
+		animator.startChangeAnimation(change)
+	}
+}
 
-	return animator.animationChangedError
+// wakeUp interrupts the sleep of the drawing goroutine, so it doesn't wait
+// for the end of a long frame
+func (animator *Animator) wakeUp() {
+	select {
+	case animator.wake <- struct{}{}:
+	default:
+	}
 }
 
-func (animator *Animator) doDraw() {
-	droppedFrameCount := 0
-	showFrameDuration := time.Duration(1000/animator.frameRate) * time.Millisecond
-	showNextFrameTime := time.Now()
+func (animator *Animator) doDraw(drawDone chan struct{}, wake chan struct{}) {
+	defer close(drawDone)
+
+	showFrameRate := 0
+	schedule := frameSchedule{nextFrameTime: animator.clock.Now()}
 	for {
-		frame := animator.getCurremtFrame()
-		if frame == nil {
+		next := animator.getCurremtFrame()
+		if next.frame == nil {
 			break
 		}
+		frame := next.frame
 		animator.shownFrame = frame
 
-		showNextFrameTime = showNextFrameTime.Add(showFrameDuration)
-		if time.Until(showNextFrameTime) <= 0 {
-			droppedFrameCount++
-			if droppedFrameCount%100 == 0 {
-				logrus.Warnf("Animator: the number of dropped frames: %v\n", droppedFrameCount)
-			}
+		if next.frameRate != showFrameRate || next.wasPaused {
+			// The frame rate has been changed or the playback has been paused,
+			// re-base the schedule
+			showFrameRate = next.frameRate
+			schedule.nextFrameTime = animator.clock.Now()
+		}
+
+		now := animator.clock.Now()
+		showFrameTime, ok := schedule.add(next, now, animator.dropPolicy)
+		if !ok {
+			animator.addDroppedFrame()
 			continue
 		}
+		animator.setLag(now.Sub(showFrameTime))
 
-		animator.paintEngine.Begin()
-		if err := frame.Draw(animator.paintEngine); err != nil {
-			panic(err)
+		err := animator.drawFrame(frame)
+		if animator.errorPolicy.Action == RetryOnError {
+			for retry := 0; err != nil && retry < animator.errorPolicy.RetryCount; retry++ {
+				err = animator.drawFrame(frame)
+			}
+		}
+		if err != nil {
+			animator.handleDrawError(err)
+		}
+		if animator.clock.Sleep(schedule.nextFrameTime.Sub(animator.clock.Now()), wake) {
+			// Stop, Pause or a forced change doesn't wait for the frame end,
+			// re-base the schedule
+			schedule.nextFrameTime = animator.clock.Now()
+		}
+	}
+
+	if animator.clearOnStop {
+		animator.clearScreen()
+	}
+}
+
+func (animator *Animator) drawFrame(frame *Frame) error {
+	paintEngine := animator.paintEngine
+	drawStartTime := animator.clock.Now()
+	if err := paintEngine.Begin(); err != nil {
+		return err
+	}
+
+	if err := frame.Draw(paintEngine); err != nil {
+		paintEngine.End()
+		return err
+	}
+
+	presentStartTime := animator.clock.Now()
+	if err := paintEngine.End(); err != nil {
+		return err
+	}
+
+	animator.addDrawnFrame(presentStartTime.Sub(drawStartTime), animator.clock.Now().Sub(presentStartTime))
+	return nil
+}
+
+func (animator *Animator) addDrawnFrame(drawDuration time.Duration, presentDuration time.Duration) {
+	animator.mutex.Lock()
+	animator.stats.framesDrawn++
+	animator.stats.drawDurations.add(drawDuration)
+	animator.stats.presentDurations.add(presentDuration)
+	animator.mutex.Unlock()
+}
+
+func (animator *Animator) addDroppedFrame() {
+	animator.mutex.Lock()
+	animator.stats.framesDropped++
+	if animator.stats.framesDropped%100 == 0 {
+		logrus.Warnf("Animator: the number of dropped frames: %v\n", animator.stats.framesDropped)
+	}
+	animator.mutex.Unlock()
+}
+
+func (animator *Animator) setLag(lag time.Duration) {
+	if lag < 0 {
+		lag = 0
+	}
+
+	animator.mutex.Lock()
+	animator.stats.lag = lag
+	animator.mutex.Unlock()
+}
+
+func (animator *Animator) handleDrawError(err error) {
+	animator.mutex.Lock()
+	defer animator.mutex.Unlock()
+
+	logrus.Warnf("Animator: failed to draw a frame of '%s': %v\n", animator.animationName, err)
+	animator.errorCount++
+	animator.lastError = err
+	animator.emit(Event{Type: EventDrawFailed, AnimationName: animator.animationName, Err: err})
+
+	switch animator.errorPolicy.Action {
+	case FallbackOnError:
+		fallbackAnimationName := animator.errorPolicy.FallbackAnimationName
+		if !animator.isRunning {
+			return
+		}
+		if animator.change == nil && animator.animationName == fallbackAnimationName {
+			return
 		}
-		animator.paintEngine.End()
-		time.Sleep(time.Until(showNextFrameTime))
+		if animator.change != nil && animator.change.animationName == fallbackAnimationName {
+			return
+		}
+		animator.startChangeAnimationNow(newAnimationChange(fallbackAnimationName))
+	case StopOnError:
+		animator.stop()
+	}
+}
+
+func (animator *Animator) clearScreen() {
+	paintEngine := animator.paintEngine
+	if err := paintEngine.Begin(); err != nil {
+		logrus.Warnf("Animator: failed to clear the screen: %v\n", err)
+		return
+	}
+
+	rect := image.Rect(0, 0, paintEngine.GetWidth(), paintEngine.GetHeight())
+	if err := paintEngine.Clear(rect); err != nil {
+		logrus.Warnf("Animator: failed to clear the screen: %v\n", err)
 	}
+
+	if err := paintEngine.End(); err != nil {
+		logrus.Warnf("Animator: failed to clear the screen: %v\n", err)
+	}
+}
+
+// scheduledFrame is a frame to draw with its timing
+type scheduledFrame struct {
+	frame     *Frame
+	frameRate int
+	// duration is the time the frame is shown
+	duration time.Duration
+	// isTransition is true for frames of transition series
+	isTransition bool
+	// wasPaused is true if the playback was paused before the frame
+	wasPaused bool
 }
 
-func (animator *Animator) getCurremtFrame() *Frame {
+func (animator *Animator) getCurremtFrame() scheduledFrame {
 	animator.mutex.Lock()
 	defer animator.mutex.Unlock()
 
+	wasPaused := animator.isPaused
+	for animator.isRunning && animator.isPaused && animator.stepCount == 0 {
+		animator.drawCond.Wait()
+	}
+
 	if !animator.isRunning {
-		return nil
+		return scheduledFrame{}
+	}
+
+	if animator.isPaused {
+		animator.stepCount--
 	}
 
+	return animator.takeFrame(wasPaused)
+}
+
+func (animator *Animator) takeFrame(wasPaused bool) scheduledFrame {
+	frame := animator.nextFrame()
+	frameRate := animator.getPlayedFrameRate()
+	duration := frame.Duration
+	if duration <= 0 {
+		duration = time.Second / time.Duration(frameRate)
+	}
+
+	return scheduledFrame{
+		frame:        frame,
+		frameRate:    frameRate,
+		duration:     duration,
+		isTransition: animator.state == asTransitionToNextAnimation,
+		wasPaused:    wasPaused,
+	}
+}
+
+func (animator *Animator) getPlayedFrameRate() int {
+	if animator.playedFrameRate > 0 {
+		return animator.playedFrameRate
+	}
+	return animator.frameRate
+}
+
+func (animator *Animator) nextFrame() *Frame {
+
 	if animator.state == asPlayCurrentAnimation {
 		return animator.getCurrentAnimationFrame()
 	}
@@ -178,42 +807,141 @@ func (animator *Animator) getCurremtFrame() *Frame {
 
 func (animator *Animator) getCurrentAnimationFrame() *Frame {
 	frame := &animator.playedFrames[animator.nextFrameNum]
-	animator.nextFrameNum = (animator.nextFrameNum + 1) % len(animator.playedFrames)
+	animator.advanceFrameNum()
+	animator.loopFrameCount++
+	if animator.loopFrameCount == getLoopLength(animator.loopMode, len(animator.playedFrames)) {
+		animator.loopFrameCount = 0
+		animator.emit(Event{Type: EventLoopWrapped, AnimationName: animator.animationName})
+		animator.onLoopWrapped()
+	}
 	return frame
 }
 
-func (animator *Animator) tryInitTransitionToNextAnimation() *Frame {
-	animator.tryInitTransitionCounter++
+func (animator *Animator) advanceFrameNum() {
+	frameCount := len(animator.playedFrames)
+	switch animator.loopMode {
+	case LoopReverse:
+		animator.nextFrameNum = (animator.nextFrameNum + frameCount - 1) % frameCount
+	case LoopPingPong:
+		if frameCount == 1 {
+			return
+		}
+		nextFrameNum := animator.nextFrameNum + animator.frameNumStep
+		if nextFrameNum < 0 || nextFrameNum >= frameCount {
+			animator.frameNumStep = -animator.frameNumStep
+			nextFrameNum = animator.nextFrameNum + animator.frameNumStep
+		}
+		animator.nextFrameNum = nextFrameNum
+	default:
+		animator.nextFrameNum = (animator.nextFrameNum + 1) % frameCount
+	}
+}
 
-	shownFrame := animator.shownFrame
-	if shownFrame == nil || !shownFrame.IsTransitionFrame() {
-		animator.checkFindTransitionFrameLooping()
-		return animator.getCurrentAnimationFrame()
+func (animator *Animator) onLoopWrapped() {
+	animator.loopCounter++
+
+	animation := animator.findAnimationByName(animator.animationName)
+	if len(animation.Variations) > 0 {
+		variations := animation.Variations
+		if animator.state == asFindTransitionFrame {
+			// Play a variation that has a transition frame to the next animation
+			transitionVariations := animator.getTransitionVariations(animation, animator.change.nextAnimationName())
+			if len(transitionVariations) > 0 {
+				variations = transitionVariations
+				// The last frame of the previous series is checked by the next try
+				animator.tryInitTransitionCounter = -1
+			}
+		}
+		animator.playFrameSeries(animator.pickVariation(variations))
+	}
+	if animation.LoopCount <= 0 || animator.loopCounter < animation.LoopCount {
+		return
 	}
 
-	transitionFrameSeriesName, ok := shownFrame.GetSeriesForTransition(*animator.nextAnimationName)
+	if animator.loopCounter == animation.LoopCount {
+		animator.emit(Event{Type: EventAnimationCompleted, AnimationName: animator.animationName})
+		animator.completePlayingChanges()
+	}
+
+	// Requested changes take precedence over the next animation
+	if animation.NextAnimationName != "" && animator.change == nil && len(animator.changeQueue) == 0 {
+		animator.startChangeAnimation(newAnimationChange(animation.NextAnimationName))
+	}
+}
+
+func (animator *Animator) completePlayingChanges() {
+	for _, change := range animator.playingChanges {
+		change.complete()
+	}
+	animator.playingChanges = nil
+}
+
+func (animator *Animator) tryInitTransitionToNextAnimation() *Frame {
+	animator.tryInitTransitionCounter++
+
+	transitionFrameSeriesName, ok := animator.getSeriesForTransition(animator.change.nextAnimationName())
 	if !ok {
 		animator.checkFindTransitionFrameLooping()
 		return animator.getCurrentAnimationFrame()
 	}
 
-	var transitionFrames []Frame
+	var transitionFrameSeries *FrameSeries
 	if transitionFrameSeriesName != "" {
 		// To go to the next animation, need to play transition frames
-		transitionFrameSeries := animator.findFrameSeriesByName(transitionFrameSeriesName)
+		transitionFrameSeries = animator.findFrameSeriesByName(transitionFrameSeriesName)
 		if transitionFrameSeries == nil {
 			err := fmt.Errorf("Could't find a series of frames named '%s'", transitionFrameSeriesName)
 			animator.finishChangeAnimation(err)
 			return animator.getCurrentAnimationFrame()
 		}
+	}
+
+	animator.startTransition(transitionFrameSeriesName, transitionFrameSeries)
+	return animator.getCurrentTransitionFrame()
+}
 
+// getSeriesForTransition gets the transition from the shown frame to the destination animation.
+// The transitions of the shown frame override the transitions of the current animation.
+func (animator *Animator) getSeriesForTransition(destAnimationName string) (string, bool) {
+	shownFrame := animator.shownFrame
+	if shownFrame == nil {
+		return "", false
+	}
+
+	if shownFrame.IsTransitionFrame() {
+		if transitionFrameSeriesName, ok := shownFrame.GetSeriesForTransition(destAnimationName); ok {
+			return transitionFrameSeriesName, true
+		}
+	}
+
+	animation := animator.findAnimationByName(animator.animationName)
+	if animation == nil {
+		return "", false
+	}
+	return animation.GetSeriesForTransition(destAnimationName)
+}
+
+// startTransition starts playing the transition frames to the next animation in the route.
+// The frame series is nil for transitions without frames.
+func (animator *Animator) startTransition(transitionFrameSeriesName string, transitionFrameSeries *FrameSeries) {
+	var transitionFrames []Frame
+	transitionFrameRate := 0
+	if transitionFrameSeries != nil {
 		transitionFrames = transitionFrameSeries.Frames
+		transitionFrameRate = transitionFrameSeries.FrameRate
 	}
 
+	animator.stats.addTransition(animator.animationName, animator.change.nextAnimationName())
+	animator.emit(Event{
+		Type:              EventTransitionStarted,
+		AnimationName:     animator.animationName,
+		DestAnimationName: animator.change.nextAnimationName(),
+		FrameSeriesName:   transitionFrameSeriesName,
+	})
 	animator.state = asTransitionToNextAnimation
 	animator.playedFrames = transitionFrames
+	animator.playedFrameRate = transitionFrameRate
 	animator.nextFrameNum = 0
-	return animator.getCurrentTransitionFrame()
 }
 
 func (animator *Animator) getCurrentTransitionFrame() *Frame {
@@ -224,31 +952,101 @@ func (animator *Animator) getCurrentTransitionFrame() *Frame {
 		return frame
 	}
 
-	animator.finishChangeAnimation(nil)
+	animator.emit(Event{
+		Type:              EventTransitionFinished,
+		AnimationName:     animator.animationName,
+		DestAnimationName: animator.change.nextAnimationName(),
+	})
+	animator.finishTransition()
 	return animator.getCurrentAnimationFrame()
 }
 
-func (animator *Animator) checkFindTransitionFrameLooping() {
-	if animator.tryInitTransitionCounter >= len(animator.playedFrames) {
-		err := fmt.Errorf("Could't find a transition frame for switch transition from '%s' to '%s'",
-			animator.animationName, *animator.nextAnimationName)
+func (animator *Animator) finishTransition() {
+	change := animator.change
+	if change.isLastHop() {
+		animator.finishChangeAnimation(nil)
+		return
+	}
+
+	// Go on to the next animation in the route
+	nextAnimationName := change.nextAnimationName()
+	if err := animator.setAnimation(nextAnimationName); err != nil {
 		animator.finishChangeAnimation(err)
+		return
 	}
+	animator.completePlayingChanges()
+	animator.emit(Event{Type: EventAnimationStarted, AnimationName: nextAnimationName})
+
+	change.hop++
+	animator.state = asInitChangeAnimation
+}
+
+func (animator *Animator) checkFindTransitionFrameLooping() {
+	if animator.tryInitTransitionCounter < getLoopLength(animator.loopMode, len(animator.playedFrames)) {
+		return
+	}
+
+	nextAnimationName := animator.change.nextAnimationName()
+	if !hasTransitionFrame(animator.playedFrames, nextAnimationName) {
+		animation := animator.findAnimationByName(animator.animationName)
+		if len(animator.getTransitionVariations(animation, nextAnimationName)) > 0 {
+			// A variation with a transition frame is played from the loop end
+			return
+		}
+	}
+
+	err := fmt.Errorf("Could't find a transition frame for switch transition from '%s' to '%s'",
+		animator.animationName, nextAnimationName)
+	animator.finishChangeAnimation(err)
+}
+
+// getTransitionVariations gets the variations of the animation that have
+// a transition frame to the destination animation
+func (animator *Animator) getTransitionVariations(animation *Animation, destAnimationName string) []Variation {
+	var variations []Variation
+	for _, variation := range animation.Variations {
+		frameSeries := animator.findFrameSeriesByName(variation.FrameSeriesName)
+		if frameSeries != nil && hasTransitionFrame(frameSeries.Frames, destAnimationName) {
+			variations = append(variations, variation)
+		}
+	}
+	return variations
+}
+
+func hasTransitionFrame(frames []Frame, destAnimationName string) bool {
+	for i := range frames {
+		if _, ok := frames[i].GetSeriesForTransition(destAnimationName); ok {
+			return true
+		}
+	}
+	return false
 }
 
 func (animator *Animator) finishChangeAnimation(err error) {
 	oldAnimation := animator.animationName
+	change := animator.change
+	animator.change = nil
 
 	if err == nil {
-		err = animator.setAnimation(*animator.nextAnimationName)
+		err = animator.setAnimation(change.animationName)
 	}
 
 	if err != nil {
 		animator.setAnimation(oldAnimation)
+		animator.emit(Event{
+			Type:              EventChangeFailed,
+			AnimationName:     oldAnimation,
+			DestAnimationName: change.animationName,
+			Err:               err,
+		})
+	} else {
+		animator.emit(Event{Type: EventAnimationStarted, AnimationName: change.animationName})
+		animator.completePlayingChanges()
+		animator.playingChanges = []*AnimationChange{change}
 	}
 
-	animator.animationChangedError = err
-	animator.animationChangedCond.Signal()
+	change.finish(err)
+	animator.startNextChangeAnimation()
 }
 
 func (animator *Animator) setAnimation(animationName string) error {
@@ -257,36 +1055,92 @@ func (animator *Animator) setAnimation(animationName string) error {
 		return fmt.Errorf("Could't find a animation named '%s'", animationName)
 	}
 
-	frameSeries := animator.findFrameSeriesByName(animation.FrameSeriesName)
-	if frameSeries == nil {
-		return fmt.Errorf("Could't find a series of frames named '%s'", animation.FrameSeriesName)
-	}
+	for _, frameSeriesName := range animation.GetFrameSeriesNames() {
+		frameSeries := animator.findFrameSeriesByName(frameSeriesName)
+		if frameSeries == nil {
+			return fmt.Errorf("Could't find a series of frames named '%s'", frameSeriesName)
+		}
 
-	if len(frameSeries.Frames) == 0 {
-		return fmt.Errorf("The frame series for animation '%s' is empty", animationName)
+		if len(frameSeries.Frames) == 0 {
+			return fmt.Errorf("The frame series for animation '%s' is empty", animationName)
+		}
 	}
 
 	animator.animationName = animationName
-	animator.playedFrames = frameSeries.Frames
-	animator.nextFrameNum = 0
+	animator.playedFrameRate = animation.FrameRate
+	animator.loopMode = animation.LoopMode
+	animator.loopCounter = 0
+	animator.playFrameSeries(animator.pickFrameSeries(animation))
+	if animation.RandomStart {
+		animator.nextFrameNum = animator.rand.Intn(len(animator.playedFrames))
+	}
 	animator.state = asPlayCurrentAnimation
 	return nil
 }
 
-func (animator *Animator) findFrameSeriesByName(frameSeriesName string) *FrameSeries {
-	for _, frameSeries := range animator.allFrameSeries {
-		if frameSeries.Name == frameSeriesName {
-			return &frameSeries
+// pickFrameSeries picks the frame series to play.
+// For animations with variations it is a random variation according to the weights.
+func (animator *Animator) pickFrameSeries(animation *Animation) *FrameSeries {
+	if len(animation.Variations) == 0 {
+		return animator.findFrameSeriesByName(animation.FrameSeriesName)
+	}
+	return animator.pickVariation(animation.Variations)
+}
+
+// pickVariation picks a random variation according to the weights
+func (animator *Animator) pickVariation(variations []Variation) *FrameSeries {
+	totalWeight := 0
+	for _, variation := range variations {
+		if variation.Weight > 0 {
+			totalWeight += variation.Weight
+		}
+	}
+
+	if totalWeight == 0 {
+		variation := variations[animator.rand.Intn(len(variations))]
+		return animator.findFrameSeriesByName(variation.FrameSeriesName)
+	}
+
+	weight := animator.rand.Intn(totalWeight)
+	for _, variation := range variations {
+		if variation.Weight <= 0 {
+			continue
 		}
+		if weight < variation.Weight {
+			return animator.findFrameSeriesByName(variation.FrameSeriesName)
+		}
+		weight -= variation.Weight
+	}
+	return nil
+}
+
+func (animator *Animator) playFrameSeries(frameSeries *FrameSeries) {
+	animator.playedFrames = frameSeries.Frames
+	animator.frameNumStep = 1
+	animator.loopFrameCount = 0
+	if animator.loopMode == LoopReverse {
+		animator.nextFrameNum = len(animator.playedFrames) - 1
+	} else {
+		animator.nextFrameNum = 0
+	}
+}
+
+func (animator *Animator) emit(event Event) {
+	for _, subscriber := range animator.subscribers {
+		subscriber.post(event)
+	}
+}
+
+func (animator *Animator) findFrameSeriesByName(frameSeriesName string) *FrameSeries {
+	if i, ok := animator.frameSeriesIndex[frameSeriesName]; ok {
+		return &animator.allFrameSeries[i]
 	}
 	return nil
 }
 
 func (animator *Animator) findAnimationByName(animationName string) *Animation {
-	for _, animation := range animator.animations {
-		if animation.Name == animationName {
-			return &animation
-		}
+	if i, ok := animator.animationIndex[animationName]; ok {
+		return &animator.animations[i]
 	}
 	return nil
 }
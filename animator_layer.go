@@ -0,0 +1,107 @@
+package chanim
+
+import (
+	"errors"
+	"time"
+)
+
+// Frame gets the frame of the animator at the given time. It implements Layer,
+// so the animator can be composited by Scene. Frame returns nil if the animator
+// isn't added to a scene or isn't running.
+// The frames behind the schedule of the animator are handled by its drop policy.
+func (animator *Animator) Frame(now time.Time) *Frame {
+	frame, _ := animator.layerFrame(now)
+	return frame
+}
+
+// layerFrame gets the frame of the animator at the given time,
+// it returns false if the frame was already returned
+func (animator *Animator) layerFrame(now time.Time) (*Frame, bool) {
+	animator.mutex.Lock()
+	defer animator.mutex.Unlock()
+
+	if animator.scene == nil || !animator.isRunning {
+		return nil, false
+	}
+
+	if animator.shownFrame != nil {
+		if now.Before(animator.layerSchedule.nextFrameTime) {
+			return animator.shownFrame, false
+		}
+
+		if animator.isPaused && animator.stepCount == 0 {
+			// Hold the frame and re-base the schedule on resume
+			animator.layerSchedule.nextFrameTime = time.Time{}
+			return animator.shownFrame, false
+		}
+	}
+
+	for {
+		if animator.isPaused && animator.stepCount > 0 {
+			animator.stepCount--
+		}
+
+		if animator.layerSchedule.nextFrameTime.IsZero() {
+			animator.layerSchedule.nextFrameTime = now
+		}
+
+		next := animator.takeFrame(false)
+		showFrameTime, ok := animator.layerSchedule.add(next, now, animator.dropPolicy)
+		if !ok && !animator.isPaused {
+			animator.stats.framesDropped++
+			continue
+		}
+
+		lag := now.Sub(showFrameTime)
+		if lag < 0 {
+			lag = 0
+		}
+		animator.stats.lag = lag
+		animator.shownFrame = next.frame
+		return next.frame, true
+	}
+}
+
+// retryLayerFrame draws the frame of the layer again according to the error policy
+func (animator *Animator) retryLayerFrame(paintEngine PaintEngine, frame *Frame, err error) error {
+	if animator.errorPolicy.Action == RetryOnError {
+		for retry := 0; err != nil && retry < animator.errorPolicy.RetryCount; retry++ {
+			err = frame.Draw(paintEngine)
+		}
+	}
+	return err
+}
+
+func (animator *Animator) attachToScene(scene *Scene) error {
+	animator.mutex.Lock()
+	defer animator.mutex.Unlock()
+
+	if animator.scene != nil {
+		return errors.New("Animator is already added to a scene")
+	}
+
+	if animator.isRunning {
+		return errors.New("Animator is already running")
+	}
+
+	animator.scene = scene
+	return nil
+}
+
+// detachFromScene stops the animator, so it can be started standalone
+func (animator *Animator) detachFromScene() {
+	animator.mutex.Lock()
+	defer animator.mutex.Unlock()
+
+	if animator.isRunning {
+		animator.stop()
+	}
+	animator.scene = nil
+}
+
+// resetLayerSchedule re-bases the schedule of the layer at the next frame
+func (animator *Animator) resetLayerSchedule() {
+	animator.mutex.Lock()
+	animator.layerSchedule = frameSchedule{}
+	animator.mutex.Unlock()
+}
@@ -0,0 +1,71 @@
+package chanim
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// AnimatorOption configures Animator
+type AnimatorOption func(animator *Animator)
+
+// WithChangeQueuePolicy sets the policy for animation changes requested
+// while another change is in progress. The default policy is RejectChanges.
+func WithChangeQueuePolicy(policy ChangeQueuePolicy) AnimatorOption {
+	return func(animator *Animator) {
+		animator.changeQueuePolicy = policy
+	}
+}
+
+// WithClock sets the time source of the animator. By default RealClock is used.
+func WithClock(clock Clock) AnimatorOption {
+	return func(animator *Animator) {
+		animator.clock = clock
+	}
+}
+
+// WithRandomSeed sets the seed of the random choices, such as animation variations
+// and random start frames. It makes the playback reproducible.
+func WithRandomSeed(seed int64) AnimatorOption {
+	return func(animator *Animator) {
+		animator.rand = rand.New(rand.NewSource(seed))
+	}
+}
+
+// WithErrorPolicy sets the policy for frames that can't be drawn.
+// By default such frames are skipped. For FallbackOnError the fallback animation
+// must exist, the animator cuts to it at once like ChangeAnimationNow.
+func WithErrorPolicy(policy ErrorPolicy) AnimatorOption {
+	return func(animator *Animator) {
+		animator.errorPolicy = policy
+	}
+}
+
+// WithDropPolicy sets the policy for frames that are behind schedule.
+// By default late frames are dropped. ResetScheduleWhenLate requires LateFrameLimit of at least 1.
+func WithDropPolicy(policy DropPolicy) AnimatorOption {
+	return func(animator *Animator) {
+		animator.dropPolicy = policy
+	}
+}
+
+// WithClearOnStop makes the animator clear the screen when it is stopped
+func WithClearOnStop() AnimatorOption {
+	return func(animator *Animator) {
+		animator.clearOnStop = true
+	}
+}
+
+// checkOptions checks the options applied to the animator
+func (animator *Animator) checkOptions() error {
+	if animator.errorPolicy.Action == FallbackOnError {
+		fallbackAnimationName := animator.errorPolicy.FallbackAnimationName
+		if animator.findAnimationByName(fallbackAnimationName) == nil {
+			return fmt.Errorf("Could't find a fallback animation named '%s'", fallbackAnimationName)
+		}
+	}
+
+	if animator.dropPolicy.Mode == ResetScheduleWhenLate && animator.dropPolicy.LateFrameLimit < 1 {
+		return fmt.Errorf("Invalid late frame limit %v", animator.dropPolicy.LateFrameLimit)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package chanim
+
+import (
+	"sort"
+	"time"
+)
+
+// maxDurationSamples is the number of the latest samples used to calculate percentiles
+const maxDurationSamples = 1024
+
+// DurationStats is statistics of durations
+type DurationStats struct {
+	Count int
+	Min   time.Duration
+	Avg   time.Duration
+	Max   time.Duration
+
+	// Percentiles of the latest samples
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+}
+
+// AnimationPair is a pair of source and destination animations
+type AnimationPair struct {
+	From string
+	To   string
+}
+
+// AnimatorStats is playback statistics of Animator
+type AnimatorStats struct {
+	FramesDrawn   int
+	FramesDropped int
+	DropPolicy    DropPolicy
+
+	// DrawDuration is the time spent drawing frames
+	DrawDuration DurationStats
+	// PresentDuration is the time spent presenting frames (PaintEngine.End)
+	PresentDuration DurationStats
+
+	// Lag is how far the last drawn frame was behind schedule
+	Lag time.Duration
+
+	// TransitionCounts is the number of started transitions per animation pair
+	TransitionCounts map[AnimationPair]int
+}
+
+type durationSampler struct {
+	count      int
+	sum        time.Duration
+	min        time.Duration
+	max        time.Duration
+	samples    []time.Duration
+	nextSample int
+}
+
+func (sampler *durationSampler) add(d time.Duration) {
+	if sampler.count == 0 || d < sampler.min {
+		sampler.min = d
+	}
+	if d > sampler.max {
+		sampler.max = d
+	}
+	sampler.count++
+	sampler.sum += d
+
+	if len(sampler.samples) < maxDurationSamples {
+		sampler.samples = append(sampler.samples, d)
+	} else {
+		sampler.samples[sampler.nextSample] = d
+		sampler.nextSample = (sampler.nextSample + 1) % maxDurationSamples
+	}
+}
+
+func (sampler *durationSampler) stats() DurationStats {
+	if sampler.count == 0 {
+		return DurationStats{}
+	}
+
+	samples := make([]time.Duration, len(sampler.samples))
+	copy(samples, sampler.samples)
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	percentile := func(p int) time.Duration {
+		return samples[(len(samples)-1)*p/100]
+	}
+
+	return DurationStats{
+		Count: sampler.count,
+		Min:   sampler.min,
+		Avg:   sampler.sum / time.Duration(sampler.count),
+		Max:   sampler.max,
+		P50:   percentile(50),
+		P90:   percentile(90),
+		P99:   percentile(99),
+	}
+}
+
+type statsCollector struct {
+	framesDrawn      int
+	framesDropped    int
+	drawDurations    durationSampler
+	presentDurations durationSampler
+	lag              time.Duration
+	transitionCounts map[AnimationPair]int
+}
+
+func (collector *statsCollector) addTransition(from string, to string) {
+	if collector.transitionCounts == nil {
+		collector.transitionCounts = make(map[AnimationPair]int)
+	}
+	collector.transitionCounts[AnimationPair{from, to}]++
+}
+
+// Stats gets the playback statistics
+func (animator *Animator) Stats() AnimatorStats {
+	animator.mutex.Lock()
+	defer animator.mutex.Unlock()
+
+	collector := &animator.stats
+	transitionCounts := make(map[AnimationPair]int)
+	for pair, count := range collector.transitionCounts {
+		transitionCounts[pair] = count
+	}
+
+	return AnimatorStats{
+		FramesDrawn:      collector.framesDrawn,
+		FramesDropped:    collector.framesDropped,
+		DropPolicy:       animator.dropPolicy,
+		DrawDuration:     collector.drawDurations.stats(),
+		PresentDuration:  collector.presentDurations.stats(),
+		Lag:              collector.lag,
+		TransitionCounts: transitionCounts,
+	}
+}
+
+// ResetStats resets the playback statistics
+func (animator *Animator) ResetStats() {
+	animator.mutex.Lock()
+	animator.stats = statsCollector{}
+	animator.mutex.Unlock()
+}
@@ -0,0 +1,77 @@
+package chanim
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDurationSampler(t *testing.T) {
+	sampler := durationSampler{}
+	if stats := sampler.stats(); stats != (DurationStats{}) {
+		t.Fatalf("Stats of no samples %+v", stats)
+	}
+
+	for i := 100; i >= 1; i-- {
+		sampler.add(time.Duration(i) * time.Millisecond)
+	}
+	expected := DurationStats{
+		Count: 100,
+		Min:   time.Millisecond,
+		Avg:   50500 * time.Microsecond,
+		Max:   100 * time.Millisecond,
+		P50:   50 * time.Millisecond,
+		P90:   90 * time.Millisecond,
+		P99:   99 * time.Millisecond,
+	}
+	if stats := sampler.stats(); stats != expected {
+		t.Fatalf("Stats %+v, expected %+v", stats, expected)
+	}
+
+	// The percentiles are calculated from the latest samples only
+	for i := 0; i < maxDurationSamples; i++ {
+		sampler.add(time.Second)
+	}
+	stats := sampler.stats()
+	if stats.Count != 100+maxDurationSamples || stats.Min != time.Millisecond || stats.P50 != time.Second {
+		t.Fatalf("Stats %+v", stats)
+	}
+}
+
+func TestAnimatorStats(t *testing.T) {
+	animations := Animations{
+		{Name: "A", FrameSeriesName: "a", Transitions: []Transition{{DestAnimationName: "B", FrameSeriesName: "ab"}}},
+		{Name: "B", FrameSeriesName: "b"},
+	}
+	allFrameSeries := []FrameSeries{makeFrameSeries("a", 2), makeFrameSeries("ab", 1), makeFrameSeries("b", 2)}
+	test := startAnimatorTest(t, animations, allFrameSeries, "A", WithDropPolicy(DropPolicy{Mode: NeverDropFrames}))
+	defer test.close()
+
+	change := test.animator.ChangeAnimationAsync(context.Background(), "B")
+	test.step(3)
+	if err := change.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	test.animator.Stop()
+
+	stats := test.animator.Stats()
+	if stats.FramesDrawn != 4 || stats.FramesDropped != 0 || stats.Lag != 0 {
+		t.Fatalf("Stats %+v", stats)
+	}
+	if stats.DropPolicy != (DropPolicy{Mode: NeverDropFrames}) {
+		t.Fatalf("Drop policy %v", stats.DropPolicy)
+	}
+	if stats.DrawDuration.Count != 4 || stats.PresentDuration.Count != 4 {
+		t.Fatalf("Duration stats %+v, %+v", stats.DrawDuration, stats.PresentDuration)
+	}
+	if expected := map[AnimationPair]int{{From: "A", To: "B"}: 1}; !reflect.DeepEqual(stats.TransitionCounts, expected) {
+		t.Fatalf("Transition counts %v, expected %v", stats.TransitionCounts, expected)
+	}
+
+	test.animator.ResetStats()
+	stats = test.animator.Stats()
+	if stats.FramesDrawn != 0 || stats.DrawDuration.Count != 0 || len(stats.TransitionCounts) != 0 {
+		t.Fatalf("Stats after reset %+v", stats)
+	}
+}
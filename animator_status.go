@@ -0,0 +1,30 @@
+package chanim
+
+// AnimatorStatus describes the animator state
+type AnimatorStatus struct {
+	IsRunning     bool
+	IsPaused      bool
+	AnimationName string
+	// IsChanging is true while an animation change is in progress
+	IsChanging bool
+
+	// ErrorCount is the number of frames that couldn't be drawn
+	ErrorCount int
+	// LastError is the last drawing error
+	LastError error
+}
+
+// Status gets the animator status
+func (animator *Animator) Status() AnimatorStatus {
+	animator.mutex.Lock()
+	defer animator.mutex.Unlock()
+
+	return AnimatorStatus{
+		IsRunning:     animator.isRunning,
+		IsPaused:      animator.isPaused,
+		AnimationName: animator.animationName,
+		IsChanging:    animator.change != nil,
+		ErrorCount:    animator.errorCount,
+		LastError:     animator.lastError,
+	}
+}
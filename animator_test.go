@@ -0,0 +1,1052 @@
+package chanim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// markOperation records its name when it is drawn on recordingPaintEngine
+type markOperation struct {
+	name string
+}
+
+func (o markOperation) Draw(paintEngine PaintEngine) error {
+	if recorder, ok := paintEngine.(*recordingPaintEngine); ok {
+		recorder.record(o.name)
+	}
+	return nil
+}
+
+// recordingPaintEngine records the names of the drawn frames
+type recordingPaintEngine struct {
+	nullPaintEngine
+	mutex  sync.Mutex
+	cond   *sync.Cond
+	frames []string
+}
+
+func newRecordingPaintEngine() *recordingPaintEngine {
+	recorder := &recordingPaintEngine{}
+	recorder.cond = sync.NewCond(&recorder.mutex)
+	return recorder
+}
+
+func (recorder *recordingPaintEngine) record(name string) {
+	recorder.mutex.Lock()
+	recorder.frames = append(recorder.frames, name)
+	recorder.cond.Broadcast()
+	recorder.mutex.Unlock()
+}
+
+// waitForFrames blocks until frameCount frames are drawn and returns the drawn frames
+func (recorder *recordingPaintEngine) waitForFrames(frameCount int) []string {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	for len(recorder.frames) < frameCount {
+		recorder.cond.Wait()
+	}
+	return append([]string(nil), recorder.frames...)
+}
+
+func (recorder *recordingPaintEngine) frameCount() int {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	return len(recorder.frames)
+}
+
+// makeFrameSeries makes a frame series whose frames are named by the series name and the frame index
+func makeFrameSeries(name string, frameCount int) FrameSeries {
+	frameSeries := FrameSeries{Name: name, Frames: make([]Frame, frameCount)}
+	for i := range frameSeries.Frames {
+		frameSeries.Frames[i].DrawOperations = []DrawOperation{markOperation{fmt.Sprintf("%s%d", name, i)}}
+	}
+	return frameSeries
+}
+
+// animatorTest drives an animator with a manual clock frame by frame
+type animatorTest struct {
+	t           *testing.T
+	animator    *Animator
+	clock       *ManualClock
+	paintEngine *recordingPaintEngine
+}
+
+func startAnimatorTest(t *testing.T, animations Animations, allFrameSeries []FrameSeries,
+	initAnimationName string, options ...AnimatorOption) *animatorTest {
+
+	test := &animatorTest{
+		t:           t,
+		clock:       NewManualClock(time.Unix(0, 0)),
+		paintEngine: newRecordingPaintEngine(),
+	}
+
+	options = append([]AnimatorOption{WithClock(test.clock), WithRandomSeed(1)}, options...)
+	animator, err := NewAnimator(test.paintEngine, animations, allFrameSeries, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	test.animator = animator
+
+	if err := animator.Start(initAnimationName); err != nil {
+		t.Fatal(err)
+	}
+	test.paintEngine.waitForFrames(1)
+	return test
+}
+
+// step advances the clock until frameCount more frames are drawn
+func (test *animatorTest) step(frameCount int) {
+	for i := 0; i < frameCount; i++ {
+		drawnFrameCount := test.paintEngine.frameCount()
+		test.clock.Advance(time.Second / defaultFrameRate)
+		test.paintEngine.waitForFrames(drawnFrameCount + 1)
+	}
+}
+
+// advance advances the clock by d once the drawing goroutine sleeps
+func (test *animatorTest) advance(d time.Duration) {
+	test.clock.WaitForSleepers(1)
+	test.clock.Advance(d)
+}
+
+func (test *animatorTest) checkFrames(expected ...string) {
+	test.t.Helper()
+	frames := test.paintEngine.waitForFrames(len(expected))
+	if !reflect.DeepEqual(frames, expected) {
+		test.t.Fatalf("Frames %v, expected %v", frames, expected)
+	}
+}
+
+func (test *animatorTest) checkAnimation(animationName string) {
+	test.t.Helper()
+	status := test.animator.Status()
+	if status.AnimationName != animationName || status.IsChanging {
+		test.t.Fatalf("Animation '%s' (changing %v), expected '%s'",
+			status.AnimationName, status.IsChanging, animationName)
+	}
+}
+
+func (test *animatorTest) close() {
+	if err := test.animator.Close(); err != nil {
+		test.t.Fatal(err)
+	}
+}
+
+// changeResult gets the error of a finished change, it fails the test if the change isn't finished
+func changeResult(t *testing.T, change *AnimationChange) error {
+	t.Helper()
+	select {
+	case <-change.Done():
+		return change.Err()
+	default:
+		t.Fatalf("Change to '%s' isn't finished", change.AnimationName())
+		return nil
+	}
+}
+
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+// connectAll adds zero-length animation transitions between all animations
+func connectAll(animations Animations) Animations {
+	for i := range animations {
+		for _, destAnimation := range animations {
+			if destAnimation.Name != animations[i].Name {
+				animations[i].Transitions = append(animations[i].Transitions,
+					Transition{DestAnimationName: destAnimation.Name})
+			}
+		}
+	}
+	return animations
+}
+
+func TestChangeAnimationWaitsForTransitionFrame(t *testing.T) {
+	a := makeFrameSeries("a", 4)
+	a.Frames[2].Transitions = []Transition{{DestAnimationName: "B"}}
+	animations := Animations{
+		{Name: "A", FrameSeriesName: "a"},
+		{Name: "B", FrameSeriesName: "b"},
+	}
+	test := startAnimatorTest(t, animations, []FrameSeries{a, makeFrameSeries("b", 2)}, "A")
+	defer test.close()
+
+	change := test.animator.ChangeAnimationAsync(context.Background(), "B")
+	test.step(2)
+	if isClosed(change.Done()) {
+		t.Fatal("Change is finished before the transition frame")
+	}
+
+	test.step(1)
+	if err := changeResult(t, change); err != nil {
+		t.Fatal(err)
+	}
+	if route := change.Route(); !reflect.DeepEqual(route, []string{"A", "B"}) {
+		t.Fatalf("Route %v", route)
+	}
+	test.checkFrames("a0", "a1", "a2", "b0")
+	test.checkAnimation("B")
+}
+
+func TestChangeAnimationPlaysTransitionSeries(t *testing.T) {
+	animations := Animations{
+		{Name: "A", FrameSeriesName: "a", Transitions: []Transition{{DestAnimationName: "B", FrameSeriesName: "ab"}}},
+		{Name: "B", FrameSeriesName: "b"},
+	}
+	allFrameSeries := []FrameSeries{makeFrameSeries("a", 2), makeFrameSeries("ab", 2), makeFrameSeries("b", 2)}
+	test := startAnimatorTest(t, animations, allFrameSeries, "A")
+	defer test.close()
+
+	change := test.animator.ChangeAnimationAsync(context.Background(), "B")
+	test.step(2)
+	if isClosed(change.Done()) {
+		t.Fatal("Change is finished before the transition series end")
+	}
+
+	test.step(1)
+	if err := changeResult(t, change); err != nil {
+		t.Fatal(err)
+	}
+	test.checkFrames("a0", "ab0", "ab1", "b0")
+	test.checkAnimation("B")
+}
+
+func TestChangeAnimationContextCancel(t *testing.T) {
+	a := makeFrameSeries("a", 3)
+	a.Frames[2].Transitions = []Transition{{DestAnimationName: "B"}}
+	animations := Animations{
+		{Name: "A", FrameSeriesName: "a"},
+		{Name: "B", FrameSeriesName: "b"},
+	}
+	test := startAnimatorTest(t, animations, []FrameSeries{a, makeFrameSeries("b", 1)}, "A")
+	defer test.close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	change := test.animator.ChangeAnimationAsync(ctx, "B")
+	cancel()
+	if err := change.Wait(); err != context.Canceled {
+		t.Fatalf("Change error %v, expected %v", err, context.Canceled)
+	}
+
+	test.step(3)
+	test.checkFrames("a0", "a1", "a2", "a0")
+	test.checkAnimation("A")
+}
+
+func TestChangeAnimationContextCancelDuringTransition(t *testing.T) {
+	animations := Animations{
+		{Name: "A", FrameSeriesName: "a", Transitions: []Transition{{DestAnimationName: "B", FrameSeriesName: "ab"}}},
+		{Name: "B", FrameSeriesName: "b"},
+	}
+	allFrameSeries := []FrameSeries{makeFrameSeries("a", 1), makeFrameSeries("ab", 3), makeFrameSeries("b", 1)}
+	test := startAnimatorTest(t, animations, allFrameSeries, "A")
+	defer test.close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	change := test.animator.ChangeAnimationAsync(ctx, "B")
+	test.step(1)
+	cancel()
+	test.step(3)
+
+	// The transition has been started, so the change goes on
+	if err := change.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	test.checkFrames("a0", "ab0", "ab1", "ab2", "b0")
+	test.checkAnimation("B")
+}
+
+func TestChangeAnimationNotRunning(t *testing.T) {
+	animations := Animations{{Name: "A", FrameSeriesName: "a"}}
+	animator, err := NewAnimator(NullPaintEngine(), animations, []FrameSeries{makeFrameSeries("a", 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := animator.ChangeAnimation("A"); err == nil {
+		t.Fatal("Animation is changed while the animator isn't running")
+	}
+}
+
+func TestFrameRate(t *testing.T) {
+	animations := Animations{
+		{Name: "A", FrameSeriesName: "a", Transitions: []Transition{{DestAnimationName: "B"}}},
+		{Name: "B", FrameSeriesName: "b", FrameRate: 5},
+	}
+	test := startAnimatorTest(t, animations, []FrameSeries{makeFrameSeries("a", 3), makeFrameSeries("b", 3)}, "A")
+	defer test.close()
+
+	if err := test.animator.SetFrameRate(0); err == nil {
+		t.Fatal("Invalid frame rate is set")
+	}
+	if err := test.animator.SetFrameRate(10); err != nil {
+		t.Fatal(err)
+	}
+	if frameRate := test.animator.GetFrameRate(); frameRate != 10 {
+		t.Fatalf("Frame rate %v", frameRate)
+	}
+
+	// The new frame rate takes effect from the next frame
+	test.advance(40 * time.Millisecond)
+	test.checkFrames("a0", "a1")
+	test.advance(60 * time.Millisecond)
+	if frameCount := test.paintEngine.frameCount(); frameCount != 2 {
+		t.Fatalf("%v frames are drawn before the frame end", frameCount)
+	}
+	test.advance(40 * time.Millisecond)
+	test.checkFrames("a0", "a1", "a2")
+
+	// The frame rate of the animation overrides the animator frame rate
+	change := test.animator.ChangeAnimationAsync(context.Background(), "B")
+	test.advance(100 * time.Millisecond)
+	test.checkFrames("a0", "a1", "a2", "b0")
+	if err := change.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	test.advance(100 * time.Millisecond)
+	test.advance(99 * time.Millisecond)
+	if frameCount := test.paintEngine.frameCount(); frameCount != 4 {
+		t.Fatalf("%v frames are drawn before the frame end", frameCount)
+	}
+	test.advance(time.Millisecond)
+	test.checkFrames("a0", "a1", "a2", "b0", "b1")
+}
+
+func TestChangeQueuePolicy(t *testing.T) {
+	tests := []struct {
+		policy        ChangeQueuePolicy
+		errs          []error
+		frames        []string
+		animationName string
+	}{
+		{
+			policy:        RejectChanges,
+			errs:          []error{nil, ErrAnimationChangeRejected, ErrAnimationChangeRejected},
+			frames:        []string{"a0", "b0", "b0", "b0"},
+			animationName: "B",
+		},
+		{
+			policy:        QueueChanges,
+			errs:          []error{nil, nil, nil},
+			frames:        []string{"a0", "b0", "c0", "a0"},
+			animationName: "A",
+		},
+		{
+			policy:        CoalesceChanges,
+			errs:          []error{nil, ErrAnimationChangeSuperseded, nil},
+			frames:        []string{"a0", "b0", "a0", "a0"},
+			animationName: "A",
+		},
+	}
+
+	for _, tt := range tests {
+		animations := connectAll(Animations{
+			{Name: "A", FrameSeriesName: "a"},
+			{Name: "B", FrameSeriesName: "b"},
+			{Name: "C", FrameSeriesName: "c"},
+		})
+		allFrameSeries := []FrameSeries{makeFrameSeries("a", 1), makeFrameSeries("b", 1), makeFrameSeries("c", 1)}
+		test := startAnimatorTest(t, animations, allFrameSeries, "A", WithChangeQueuePolicy(tt.policy))
+
+		changes := []*AnimationChange{
+			test.animator.ChangeAnimationAsync(context.Background(), "B"),
+			test.animator.ChangeAnimationAsync(context.Background(), "C"),
+			test.animator.ChangeAnimationAsync(context.Background(), "A"),
+		}
+		test.step(3)
+
+		for i, change := range changes {
+			if err := changeResult(t, change); err != tt.errs[i] {
+				t.Errorf("%v: change %v: error %v, expected %v", tt.policy, i, err, tt.errs[i])
+			}
+		}
+		test.checkFrames(tt.frames...)
+		test.checkAnimation(tt.animationName)
+		test.close()
+	}
+}
+
+func TestCancelQueuedChange(t *testing.T) {
+	animations := connectAll(Animations{
+		{Name: "A", FrameSeriesName: "a"},
+		{Name: "B", FrameSeriesName: "b"},
+		{Name: "C", FrameSeriesName: "c"},
+	})
+	allFrameSeries := []FrameSeries{makeFrameSeries("a", 1), makeFrameSeries("b", 1), makeFrameSeries("c", 1)}
+	test := startAnimatorTest(t, animations, allFrameSeries, "A", WithChangeQueuePolicy(QueueChanges))
+	defer test.close()
+
+	change := test.animator.ChangeAnimationAsync(context.Background(), "B")
+	ctx, cancel := context.WithCancel(context.Background())
+	queuedChange := test.animator.ChangeAnimationAsync(ctx, "C")
+	cancel()
+	if err := queuedChange.Wait(); err != context.Canceled {
+		t.Fatalf("Queued change error %v, expected %v", err, context.Canceled)
+	}
+
+	test.step(2)
+	if err := changeResult(t, change); err != nil {
+		t.Fatal(err)
+	}
+	test.checkFrames("a0", "b0", "b0")
+	test.checkAnimation("B")
+}
+
+func TestChangeAnimationRoutesThroughIntermediateAnimations(t *testing.T) {
+	a := makeFrameSeries("a", 2)
+	a.Frames[0].Transitions = []Transition{{DestAnimationName: "B"}}
+	b := makeFrameSeries("b", 2)
+	b.Frames[0].Transitions = []Transition{{DestAnimationName: "C"}}
+	animations := Animations{
+		{Name: "A", FrameSeriesName: "a"},
+		{Name: "B", FrameSeriesName: "b"},
+		{Name: "C", FrameSeriesName: "c"},
+	}
+	test := startAnimatorTest(t, animations, []FrameSeries{a, b, makeFrameSeries("c", 1)}, "A")
+	defer test.close()
+
+	route, err := test.animator.FindRoute("A", "C")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(route, []string{"A", "B", "C"}) {
+		t.Fatalf("Route %v", route)
+	}
+	if _, err := test.animator.FindRoute("C", "A"); err == nil {
+		t.Fatal("Route from 'C' to 'A' is found")
+	}
+
+	change := test.animator.ChangeAnimationAsync(context.Background(), "C")
+	test.step(2)
+	if err := changeResult(t, change); err != nil {
+		t.Fatal(err)
+	}
+	if route := change.Route(); !reflect.DeepEqual(route, []string{"A", "B", "C"}) {
+		t.Fatalf("Change route %v", route)
+	}
+	test.checkFrames("a0", "b0", "c0")
+	test.checkAnimation("C")
+}
+
+func TestCancelChangeAfterFirstHop(t *testing.T) {
+	a := makeFrameSeries("a", 1)
+	a.Frames[0].Transitions = []Transition{{DestAnimationName: "B"}}
+	b := makeFrameSeries("b", 2)
+	b.Frames[1].Transitions = []Transition{{DestAnimationName: "C"}}
+	animations := Animations{
+		{Name: "A", FrameSeriesName: "a"},
+		{Name: "B", FrameSeriesName: "b"},
+		{Name: "C", FrameSeriesName: "c"},
+	}
+	test := startAnimatorTest(t, animations, []FrameSeries{a, b, makeFrameSeries("c", 1)}, "A")
+	defer test.close()
+
+	change := test.animator.ChangeAnimationAsync(context.Background(), "C")
+	test.step(1)
+	test.checkFrames("a0", "b0")
+
+	// The animator has left 'A', so the change goes on to 'C'
+	test.animator.cancelChangeAnimation(change, context.Canceled)
+	if isClosed(change.Done()) {
+		t.Fatalf("Change is cancelled on the intermediate animation: %v", change.Err())
+	}
+
+	test.step(2)
+	if err := changeResult(t, change); err != nil {
+		t.Fatal(err)
+	}
+	test.checkFrames("a0", "b0", "b1", "c0")
+	test.checkAnimation("C")
+}
+
+func TestSubscribe(t *testing.T) {
+	animations := Animations{
+		{Name: "A", FrameSeriesName: "a", Transitions: []Transition{{DestAnimationName: "B", FrameSeriesName: "ab"}}},
+		{Name: "B", FrameSeriesName: "b"},
+	}
+	allFrameSeries := []FrameSeries{makeFrameSeries("a", 3), makeFrameSeries("ab", 1), makeFrameSeries("b", 3)}
+	test := startAnimatorTest(t, animations, allFrameSeries, "A")
+	defer test.close()
+
+	events := make(chan Event, 16)
+	unsubscribe := test.animator.Subscribe(func(event Event) {
+		events <- event
+	})
+
+	change := test.animator.ChangeAnimationAsync(context.Background(), "B")
+	test.step(2)
+	if err := changeResult(t, change); err != nil {
+		t.Fatal(err)
+	}
+	if err := test.animator.ChangeAnimation("X"); err == nil {
+		t.Fatal("Animation is changed to a missing animation")
+	}
+
+	expected := []Event{
+		{Type: EventTransitionStarted, AnimationName: "A", DestAnimationName: "B", FrameSeriesName: "ab"},
+		{Type: EventTransitionFinished, AnimationName: "A", DestAnimationName: "B"},
+		{Type: EventAnimationStarted, AnimationName: "B"},
+		{Type: EventChangeFailed, AnimationName: "B", DestAnimationName: "X"},
+	}
+	for _, expectedEvent := range expected {
+		select {
+		case event := <-events:
+			if (event.Err != nil) != (expectedEvent.Type == EventChangeFailed) {
+				t.Fatalf("Event %+v has unexpected error", event)
+			}
+			event.Err = nil
+			if event != expectedEvent {
+				t.Fatalf("Event %+v, expected %+v", event, expectedEvent)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Event %+v isn't received", expectedEvent)
+		}
+	}
+
+	unsubscribe()
+	test.step(1)
+	test.animator.ChangeAnimation("X")
+	select {
+	case event := <-events:
+		t.Fatalf("Event %+v is received after unsubscribing", event)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestOneShotAnimationCompletes(t *testing.T) {
+	animations := connectAll(Animations{
+		{Name: "A", FrameSeriesName: "a"},
+		{Name: "B", FrameSeriesName: "b", LoopCount: 1, NextAnimationName: "A"},
+	})
+	test := startAnimatorTest(t, animations, []FrameSeries{makeFrameSeries("a", 1), makeFrameSeries("b", 2)}, "A")
+	defer test.close()
+
+	change := test.animator.ChangeAnimationAsync(context.Background(), "B")
+	test.step(1)
+	if err := changeResult(t, change); err != nil {
+		t.Fatal(err)
+	}
+	if isClosed(change.Completed()) {
+		t.Fatal("Change is completed before the loop end")
+	}
+
+	test.step(1)
+	if !isClosed(change.Completed()) {
+		t.Fatal("Change isn't completed after the loop end")
+	}
+
+	test.step(1)
+	test.checkFrames("a0", "b0", "b1", "a0")
+	test.checkAnimation("A")
+}
+
+func TestStopFailsPendingChanges(t *testing.T) {
+	a := makeFrameSeries("a", 3)
+	a.Frames[2].Transitions = []Transition{{DestAnimationName: "B"}}
+	animations := Animations{
+		{Name: "A", FrameSeriesName: "a"},
+		{Name: "B", FrameSeriesName: "b"},
+	}
+	test := startAnimatorTest(t, animations, []FrameSeries{a, makeFrameSeries("b", 1)}, "A")
+	defer test.close()
+
+	change := test.animator.ChangeAnimationAsync(context.Background(), "B")
+	test.animator.Stop()
+	if err := changeResult(t, change); err != ErrAnimatorStopped {
+		t.Fatalf("Change error %v, expected %v", err, ErrAnimatorStopped)
+	}
+	if test.animator.Status().IsRunning {
+		t.Fatal("Animator is running after Stop")
+	}
+
+	// A stopped animator can be started again
+	if err := test.animator.Start("B"); err != nil {
+		t.Fatal(err)
+	}
+	test.checkFrames("a0", "b0")
+}
+
+// closingPaintEngine counts the calls of Close
+type closingPaintEngine struct {
+	nullPaintEngine
+	closeCount int
+}
+
+func (paintEngine *closingPaintEngine) Close() error {
+	paintEngine.closeCount++
+	return nil
+}
+
+func TestClose(t *testing.T) {
+	paintEngine := &closingPaintEngine{}
+	animator, err := NewAnimator(paintEngine, Animations{{Name: "A", FrameSeriesName: "a"}},
+		[]FrameSeries{makeFrameSeries("a", 1)}, WithClock(NewManualClock(time.Unix(0, 0))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := animator.Start("A"); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := animator.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if paintEngine.closeCount != 1 {
+		t.Fatalf("Paint engine is closed %v times", paintEngine.closeCount)
+	}
+	if err := animator.Start("A"); err == nil {
+		t.Fatal("Closed animator is started")
+	}
+}
+
+var errTestDraw = errors.New("Test draw error")
+
+// failingOperation fails the first failCount draws
+type failingOperation struct {
+	failCount *int
+}
+
+func (o failingOperation) Draw(paintEngine PaintEngine) error {
+	if *o.failCount > 0 {
+		*o.failCount--
+		return errTestDraw
+	}
+	return nil
+}
+
+// makeFailingFrame makes the frame record its name and then fail failCount times
+func makeFailingFrame(frame *Frame, failCount int) {
+	frame.DrawOperations = append(frame.DrawOperations, failingOperation{&failCount})
+}
+
+func TestSkipAndRetryOnError(t *testing.T) {
+	tests := []struct {
+		policy     ErrorPolicy
+		failCount  int
+		frames     []string
+		errorCount int
+	}{
+		{ErrorPolicy{Action: SkipFrameOnError}, 1, []string{"a0", "a1", "a2"}, 1},
+		{ErrorPolicy{Action: RetryOnError, RetryCount: 2}, 2, []string{"a0", "a1", "a1", "a1", "a2"}, 0},
+		{ErrorPolicy{Action: RetryOnError, RetryCount: 1}, 1000, []string{"a0", "a1", "a1", "a2"}, 1},
+	}
+
+	for _, tt := range tests {
+		a := makeFrameSeries("a", 3)
+		makeFailingFrame(&a.Frames[1], tt.failCount)
+		test := startAnimatorTest(t, Animations{{Name: "A", FrameSeriesName: "a"}}, []FrameSeries{a}, "A",
+			WithErrorPolicy(tt.policy))
+		test.step(2)
+		test.checkFrames(tt.frames...)
+
+		status := test.animator.Status()
+		if status.ErrorCount != tt.errorCount || !status.IsRunning {
+			t.Errorf("%v: status %+v", tt.policy.Action, status)
+		}
+		if tt.errorCount > 0 && status.LastError != errTestDraw {
+			t.Errorf("%v: last error %v", tt.policy.Action, status.LastError)
+		}
+		test.close()
+	}
+}
+
+func TestStopOnError(t *testing.T) {
+	a := makeFrameSeries("a", 3)
+	makeFailingFrame(&a.Frames[1], 1)
+	test := startAnimatorTest(t, Animations{{Name: "A", FrameSeriesName: "a"}}, []FrameSeries{a}, "A",
+		WithErrorPolicy(ErrorPolicy{Action: StopOnError}))
+	defer test.close()
+
+	test.animator.mutex.Lock()
+	drawDone := test.animator.drawDone
+	test.animator.mutex.Unlock()
+
+	test.step(1)
+	select {
+	case <-drawDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Animator isn't stopped on error")
+	}
+
+	test.checkFrames("a0", "a1")
+	if status := test.animator.Status(); status.IsRunning || status.ErrorCount != 1 {
+		t.Fatalf("Status %+v", status)
+	}
+}
+
+func TestFallbackOnError(t *testing.T) {
+	a := makeFrameSeries("a", 3)
+	a.Frames[2].Transitions = []Transition{{DestAnimationName: "B"}}
+	makeFailingFrame(&a.Frames[1], 1)
+	animations := Animations{
+		{Name: "A", FrameSeriesName: "a"},
+		{Name: "B", FrameSeriesName: "b"},
+		{Name: "F", FrameSeriesName: "f"},
+	}
+	allFrameSeries := []FrameSeries{a, makeFrameSeries("b", 1), makeFrameSeries("f", 1)}
+	policy := ErrorPolicy{Action: FallbackOnError, FallbackAnimationName: "F"}
+	test := startAnimatorTest(t, animations, allFrameSeries, "A", WithErrorPolicy(policy))
+	defer test.close()
+
+	change := test.animator.ChangeAnimationAsync(context.Background(), "B")
+	test.step(1)
+
+	// The fallback cuts to 'F' without waiting for the frame end
+	test.checkFrames("a0", "a1", "f0")
+	test.checkAnimation("F")
+	if err := changeResult(t, change); err != ErrAnimationChangeSuperseded {
+		t.Fatalf("Change error %v, expected %v", err, ErrAnimationChangeSuperseded)
+	}
+
+	policy.FallbackAnimationName = "X"
+	if _, err := NewAnimator(NullPaintEngine(), animations, allFrameSeries, WithErrorPolicy(policy)); err == nil {
+		t.Fatal("Missing fallback animation is accepted")
+	}
+}
+
+func TestPauseAndStep(t *testing.T) {
+	test := startAnimatorTest(t, Animations{{Name: "A", FrameSeriesName: "a"}}, []FrameSeries{makeFrameSeries("a", 3)}, "A")
+	defer test.close()
+
+	if err := test.animator.Step(1); err == nil {
+		t.Fatal("Running animator is stepped")
+	}
+
+	test.animator.Pause()
+	if !test.animator.Status().IsPaused {
+		t.Fatal("Animator isn't paused")
+	}
+	test.clock.Advance(time.Second)
+	if frameCount := test.paintEngine.frameCount(); frameCount != 1 {
+		t.Fatalf("%v frames are drawn while paused", frameCount)
+	}
+
+	if err := test.animator.Step(0); err == nil {
+		t.Fatal("Invalid frame count is accepted")
+	}
+	if err := test.animator.Step(2); err != nil {
+		t.Fatal(err)
+	}
+	test.checkFrames("a0", "a1")
+	test.advance(40 * time.Millisecond)
+	test.checkFrames("a0", "a1", "a2")
+	test.advance(time.Second)
+	if frameCount := test.paintEngine.frameCount(); frameCount != 3 {
+		t.Fatalf("%v frames are drawn after the steps", frameCount)
+	}
+
+	test.animator.Resume()
+	if test.animator.Status().IsPaused {
+		t.Fatal("Animator is paused after Resume")
+	}
+	test.checkFrames("a0", "a1", "a2", "a0")
+	test.step(1)
+	test.checkFrames("a0", "a1", "a2", "a0", "a1")
+}
+
+func TestStopInterruptsLongFrame(t *testing.T) {
+	a := makeFrameSeries("a", 1)
+	a.Frames[0].Duration = time.Hour
+	test := startAnimatorTest(t, Animations{{Name: "A", FrameSeriesName: "a"}}, []FrameSeries{a}, "A")
+	defer test.close()
+	test.clock.WaitForSleepers(1)
+
+	stopped := make(chan struct{})
+	go func() {
+		test.animator.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop waits for the frame end")
+	}
+}
+
+func TestFrameDuration(t *testing.T) {
+	a := makeFrameSeries("a", 3)
+	a.Frames[1].Duration = 100 * time.Millisecond
+	test := startAnimatorTest(t, Animations{{Name: "A", FrameSeriesName: "a"}}, []FrameSeries{a}, "A")
+	defer test.close()
+
+	test.step(1)
+	test.advance(40 * time.Millisecond)
+	test.advance(59 * time.Millisecond)
+	if frameCount := test.paintEngine.frameCount(); frameCount != 2 {
+		t.Fatalf("%v frames are drawn before the frame end", frameCount)
+	}
+
+	test.advance(time.Millisecond)
+	test.checkFrames("a0", "a1", "a2")
+	test.step(1)
+	test.checkFrames("a0", "a1", "a2", "a0")
+}
+
+func TestLoopModes(t *testing.T) {
+	tests := []struct {
+		loopMode LoopMode
+		frames   []string
+	}{
+		{LoopForward, []string{"a0", "a1", "a2", "a0", "a1", "a2"}},
+		{LoopReverse, []string{"a2", "a1", "a0", "a2", "a1", "a0"}},
+		{LoopPingPong, []string{"a0", "a1", "a2", "a1", "a0", "a1"}},
+	}
+
+	for _, tt := range tests {
+		animations := Animations{{Name: "A", FrameSeriesName: "a", LoopMode: tt.loopMode}}
+		test := startAnimatorTest(t, animations, []FrameSeries{makeFrameSeries("a", 3)}, "A")
+		test.step(len(tt.frames) - 1)
+		frames := test.paintEngine.waitForFrames(len(tt.frames))
+		if !reflect.DeepEqual(frames, tt.frames) {
+			t.Errorf("%v: frames %v, expected %v", tt.loopMode, frames, tt.frames)
+		}
+		test.close()
+	}
+}
+
+func TestRandomStart(t *testing.T) {
+	animations := Animations{{Name: "A", FrameSeriesName: "a", RandomStart: true}}
+	startFrames := make(map[string]bool)
+	for seed := int64(0); seed < 10; seed++ {
+		test := startAnimatorTest(t, animations, []FrameSeries{makeFrameSeries("a", 10)}, "A", WithRandomSeed(seed))
+		test.step(1)
+		frames := test.paintEngine.waitForFrames(2)
+		startFrames[frames[0]] = true
+
+		var frameNum int
+		fmt.Sscanf(frames[0], "a%d", &frameNum)
+		if expected := fmt.Sprintf("a%d", (frameNum+1)%10); frames[1] != expected {
+			t.Errorf("Seed %v: frame %s after %s, expected %s", seed, frames[1], frames[0], expected)
+		}
+		test.close()
+	}
+
+	if len(startFrames) < 2 {
+		t.Fatalf("Animation starts from %v only", startFrames)
+	}
+}
+
+func TestChangeAnimationFindsTransitionInVariation(t *testing.T) {
+	v := makeFrameSeries("v", 2)
+	v.Frames[1].Transitions = []Transition{{DestAnimationName: "B"}}
+	animations := Animations{
+		{Name: "A", Variations: []Variation{{FrameSeriesName: "a", Weight: 99}, {FrameSeriesName: "v", Weight: 1}}},
+		{Name: "B", FrameSeriesName: "b"},
+	}
+	allFrameSeries := []FrameSeries{makeFrameSeries("a", 3), v, makeFrameSeries("b", 1)}
+
+	for seed := int64(0); seed < 20; seed++ {
+		test := startAnimatorTest(t, animations, allFrameSeries, "A", WithRandomSeed(seed))
+		change := test.animator.ChangeAnimationAsync(context.Background(), "B")
+		for i := 0; i < 10 && !isClosed(change.Done()); i++ {
+			test.step(1)
+		}
+
+		frames := test.paintEngine.waitForFrames(0)
+		if err := changeResult(t, change); err != nil {
+			t.Fatalf("Seed %v: %v, frames %v", seed, err, frames)
+		}
+		if n := len(frames); n < 2 || frames[n-2] != "v1" || frames[n-1] != "b0" {
+			t.Fatalf("Seed %v: frames %v", seed, frames)
+		}
+		test.close()
+	}
+}
+
+func TestPickVariation(t *testing.T) {
+	allFrameSeries := []FrameSeries{makeFrameSeries("a", 1), makeFrameSeries("v", 1)}
+	tests := []struct {
+		variations []Variation
+		picked     map[string]bool
+	}{
+		{[]Variation{{"a", 0}, {"v", 1}}, map[string]bool{"v": true}},
+		{[]Variation{{"a", 3}, {"v", -1}}, map[string]bool{"a": true}},
+		{[]Variation{{"a", 1}, {"v", 1}}, map[string]bool{"a": true, "v": true}},
+		{[]Variation{{"a", 0}, {"v", 0}}, map[string]bool{"a": true, "v": true}},
+	}
+
+	for _, tt := range tests {
+		animations := Animations{{Name: "A", Variations: tt.variations}}
+		animator, err := NewAnimator(NullPaintEngine(), animations, allFrameSeries, WithRandomSeed(1))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		picked := make(map[string]bool)
+		for i := 0; i < 100; i++ {
+			picked[animator.pickVariation(tt.variations).Name] = true
+		}
+		if !reflect.DeepEqual(picked, tt.picked) {
+			t.Errorf("Variations %v: picked %v, expected %v", tt.variations, picked, tt.picked)
+		}
+	}
+}
+
+func TestChangeAnimationNowSupersedesPendingChange(t *testing.T) {
+	a := makeFrameSeries("a", 3)
+	a.Frames[2].Transitions = []Transition{{DestAnimationName: "B"}}
+	for i := range a.Frames {
+		a.Frames[i].Duration = time.Hour
+	}
+	animations := Animations{
+		{Name: "A", FrameSeriesName: "a"},
+		{Name: "B", FrameSeriesName: "b"},
+		{Name: "C", FrameSeriesName: "c"},
+	}
+	allFrameSeries := []FrameSeries{a, makeFrameSeries("b", 1), makeFrameSeries("c", 1)}
+	test := startAnimatorTest(t, animations, allFrameSeries, "A")
+	defer test.close()
+
+	pendingChange := test.animator.ChangeAnimationAsync(context.Background(), "B")
+	if err := test.animator.ChangeAnimationNow(context.Background(), "C"); err != nil {
+		t.Fatal(err)
+	}
+	if err := pendingChange.Wait(); err != ErrAnimationChangeSuperseded {
+		t.Fatalf("Pending change error %v, expected %v", err, ErrAnimationChangeSuperseded)
+	}
+
+	// The forced change doesn't wait for the end of the long frame
+	test.checkFrames("a0", "c0")
+	test.checkAnimation("C")
+}
+
+func TestChangeAnimationNowPlaysInterruptSeries(t *testing.T) {
+	animations := Animations{
+		{Name: "A", FrameSeriesName: "a"},
+		{Name: "B", FrameSeriesName: "b", InterruptFrameSeriesName: "i"},
+	}
+	allFrameSeries := []FrameSeries{makeFrameSeries("a", 1), makeFrameSeries("b", 1), makeFrameSeries("i", 2)}
+	test := startAnimatorTest(t, animations, allFrameSeries, "A")
+	defer test.close()
+
+	change := test.animator.ChangeAnimationNowAsync(context.Background(), "B")
+	if route := change.Route(); !reflect.DeepEqual(route, []string{"A", "B"}) {
+		t.Fatalf("Route %v", route)
+	}
+	test.checkFrames("a0", "i0")
+	if isClosed(change.Done()) {
+		t.Fatal("Change is finished before the interrupt series end")
+	}
+
+	test.step(2)
+	if err := changeResult(t, change); err != nil {
+		t.Fatal(err)
+	}
+	test.checkFrames("a0", "i0", "i1", "b0")
+	test.checkAnimation("B")
+}
+
+func TestChangeAnimationNowContext(t *testing.T) {
+	animations := Animations{
+		{Name: "A", FrameSeriesName: "a"},
+		{Name: "B", FrameSeriesName: "b", InterruptFrameSeriesName: "i"},
+	}
+	allFrameSeries := []FrameSeries{makeFrameSeries("a", 1), makeFrameSeries("b", 1), makeFrameSeries("i", 2)}
+	test := startAnimatorTest(t, animations, allFrameSeries, "A")
+	defer test.close()
+
+	// The change isn't started with a done context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := test.animator.ChangeAnimationNow(ctx, "B"); err != context.Canceled {
+		t.Fatalf("Change error %v, expected %v", err, context.Canceled)
+	}
+	test.checkAnimation("A")
+
+	// The wait for the interrupt series is cancelled, the change goes on
+	ctx, cancel = context.WithCancel(context.Background())
+	result := make(chan error)
+	go func() {
+		result <- test.animator.ChangeAnimationNow(ctx, "B")
+	}()
+	test.checkFrames("a0", "i0")
+	cancel()
+	if err := <-result; err != context.Canceled {
+		t.Fatalf("Change error %v, expected %v", err, context.Canceled)
+	}
+
+	test.step(2)
+	test.checkFrames("a0", "i0", "i1", "b0")
+	test.checkAnimation("B")
+}
+
+func TestGetAnimationAndFrameSeries(t *testing.T) {
+	animations := Animations{
+		{Name: "A", FrameSeriesName: "a", Transitions: []Transition{{DestAnimationName: "B"}}},
+		{Name: "B", FrameSeriesName: "b", Transitions: []Transition{{DestAnimationName: "A"}}},
+	}
+	animator, err := NewAnimator(NullPaintEngine(), animations, []FrameSeries{makeFrameSeries("a", 1), makeFrameSeries("b", 2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if names := animator.GetAnimationNames(); !reflect.DeepEqual(names, []string{"A", "B"}) {
+		t.Fatalf("Animation names %v", names)
+	}
+	if names := animator.GetFrameSeriesNames(); !reflect.DeepEqual(names, []string{"a", "b"}) {
+		t.Fatalf("Frame series names %v", names)
+	}
+
+	if animation := animator.GetAnimation("B"); animation == nil || animation.FrameSeriesName != "b" {
+		t.Fatalf("Animation %+v", animation)
+	}
+	if frameSeries := animator.GetFrameSeries("b"); frameSeries == nil || len(frameSeries.Frames) != 2 {
+		t.Fatalf("Frame series %+v", frameSeries)
+	}
+	if animator.GetAnimation("X") != nil || animator.GetFrameSeries("x") != nil {
+		t.Fatal("Missing animation or frame series is found")
+	}
+
+	// The animator keeps its own copy of the animations
+	animations[1].FrameSeriesName = "a"
+	if animation := animator.GetAnimation("B"); animation.FrameSeriesName != "b" {
+		t.Fatalf("Animation is changed by the caller: %+v", animation)
+	}
+}
+
+func TestAnimationTransitions(t *testing.T) {
+	tests := []struct {
+		name   string
+		steps  int
+		frames []string
+	}{
+		{"from any frame", 1, []string{"a0", "a1", "b0"}},
+		{"frame transition overrides", 2, []string{"a0", "a1", "a2", "fb0", "b0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := makeFrameSeries("a", 4)
+			a.Frames[2].Transitions = []Transition{{DestAnimationName: "B", FrameSeriesName: "fb"}}
+			animations := Animations{
+				{Name: "A", FrameSeriesName: "a", Transitions: []Transition{{DestAnimationName: "B"}}},
+				{Name: "B", FrameSeriesName: "b"},
+			}
+			allFrameSeries := []FrameSeries{a, makeFrameSeries("fb", 1), makeFrameSeries("b", 2)}
+			test := startAnimatorTest(t, animations, allFrameSeries, "A")
+			defer test.close()
+
+			test.step(tt.steps)
+			change := test.animator.ChangeAnimationAsync(context.Background(), "B")
+			test.step(len(tt.frames) - tt.steps - 1)
+			if err := changeResult(t, change); err != nil {
+				t.Fatal(err)
+			}
+			test.checkFrames(tt.frames...)
+			test.checkAnimation("B")
+		})
+	}
+}
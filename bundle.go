@@ -0,0 +1,249 @@
+package chanim
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"syscall"
+)
+
+// bundleMagic starts every bundle file
+var bundleMagic = [8]byte{'C', 'H', 'A', 'N', 'I', 'M', 'B', '1'}
+
+// bundleHeader is followed by the manifest, the index entries and the pixmap data
+type bundleHeader struct {
+	Magic        [8]byte
+	ManifestSize uint32
+	EntryCount   uint32
+}
+
+// bundleEntry describes a packed pixmap in the bundle.
+// It is stored after the name of the pixmap.
+type bundleEntry struct {
+	PixFormat  uint32
+	Width      uint32
+	Height     uint32
+	DataOffset uint64
+	DataSize   uint64
+}
+
+// Bundle is a character loaded from a single bundle file.
+// The packed pixmaps of the character refer to the memory mapped file,
+// so they must not be used after Close.
+type Bundle struct {
+	Character
+	data []byte
+}
+
+// WriteBundle writes the manifest and the packed pixmaps to a bundle file.
+// The pixmaps are keyed by the names the globs of the manifest are matched against.
+func WriteBundle(fileName string, manifest []byte, pixmaps map[string]*PackedPixmap) error {
+	names := make([]string, 0, len(pixmaps))
+	for name := range pixmaps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	header := bundleHeader{
+		Magic:        bundleMagic,
+		ManifestSize: uint32(len(manifest)),
+		EntryCount:   uint32(len(names)),
+	}
+	dataOffset := uint64(binary.Size(header) + len(manifest))
+	for _, name := range names {
+		dataOffset += uint64(4 + len(name) + binary.Size(bundleEntry{}))
+	}
+
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// The writer keeps the first error, it is returned by Flush
+	writer := bufio.NewWriter(file)
+	binary.Write(writer, binary.LittleEndian, header)
+	writer.Write(manifest)
+	for _, name := range names {
+		pp := pixmaps[name]
+		binary.Write(writer, binary.LittleEndian, uint32(len(name)))
+		writer.WriteString(name)
+		binary.Write(writer, binary.LittleEndian, bundleEntry{
+			PixFormat:  uint32(pp.PixFormat),
+			Width:      uint32(pp.Width),
+			Height:     uint32(pp.Height),
+			DataOffset: dataOffset,
+			DataSize:   uint64(len(pp.Data)),
+		})
+		dataOffset += uint64(len(pp.Data))
+	}
+	for _, name := range names {
+		writer.Write(pixmaps[name].Data)
+	}
+
+	err = writer.Flush()
+	if err != nil {
+		return err
+	}
+
+	return file.Sync()
+}
+
+// OpenBundle maps the bundle file to memory and loads the character from it.
+// The globs of the manifest are matched against the pixmap names,
+// the "mmap" flag of the frame series is ignored.
+func OpenBundle(fileName string) (*Bundle, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	fileSize := int(fileInfo.Size())
+	if fileSize < binary.Size(bundleHeader{}) {
+		return nil, fmt.Errorf("%s: Invalid bundle", fileName)
+	}
+
+	data, err := syscall.Mmap(int(file.Fd()), 0, fileSize, syscall.PROT_READ, syscall.MAP_PRIVATE)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := &Bundle{data: data}
+	if err = bundle.load(); err != nil {
+		syscall.Munmap(data)
+		return nil, fmt.Errorf("%s: %v", fileName, err)
+	}
+
+	return bundle, nil
+}
+
+func (bundle *Bundle) load() error {
+	reader := bytes.NewReader(bundle.data)
+
+	header := bundleHeader{}
+	if err := binary.Read(reader, binary.LittleEndian, &header); err != nil {
+		return err
+	}
+	if header.Magic != bundleMagic {
+		return errors.New("Invalid bundle")
+	}
+
+	if int64(header.ManifestSize) > int64(reader.Len()) {
+		return errors.New("Invalid manifest")
+	}
+	manifest := make([]byte, header.ManifestSize)
+	io.ReadFull(reader, manifest)
+
+	source := bundlePixmapSource{pixmaps: make(map[string]*PackedPixmap)}
+	for i := 0; i < int(header.EntryCount); i++ {
+		var nameSize uint32
+		if err := binary.Read(reader, binary.LittleEndian, &nameSize); err != nil {
+			return err
+		}
+		if int64(nameSize) > int64(reader.Len()) {
+			return errors.New("Invalid index")
+		}
+		name := make([]byte, nameSize)
+		io.ReadFull(reader, name)
+
+		entry := bundleEntry{}
+		if err := binary.Read(reader, binary.LittleEndian, &entry); err != nil {
+			return err
+		}
+
+		pp, err := bundle.getPackedPixmap(entry)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		source.pixmaps[string(name)] = pp
+		source.names = append(source.names, string(name))
+	}
+	sortNaturally(source.names)
+
+	character, err := parseCharacter(manifest, source)
+	if err != nil {
+		return err
+	}
+	bundle.Character = *character
+	return nil
+}
+
+func (bundle *Bundle) getPackedPixmap(entry bundleEntry) (*PackedPixmap, error) {
+	pixFormat, err := u32ToPixFormat(entry.PixFormat)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Width > 32000 {
+		return nil, errors.New("Invalid width")
+	}
+	if entry.Height > 32000 {
+		return nil, errors.New("Invalid height")
+	}
+
+	dataSize := uint64(len(bundle.data))
+	if entry.DataOffset > dataSize || entry.DataSize > dataSize-entry.DataOffset {
+		return nil, errors.New("Invalid data")
+	}
+
+	return &PackedPixmap{
+		Data:      bundle.data[entry.DataOffset : entry.DataOffset+entry.DataSize],
+		Width:     int(entry.Width),
+		Height:    int(entry.Height),
+		PixFormat: pixFormat,
+	}, nil
+}
+
+// Close unmaps the bundle file
+func (bundle *Bundle) Close() error {
+	if bundle.data == nil {
+		return nil
+	}
+
+	data := bundle.data
+	bundle.data = nil
+	return syscall.Munmap(data)
+}
+
+// bundlePixmapSource finds the packed pixmaps in the bundle index
+type bundlePixmapSource struct {
+	pixmaps map[string]*PackedPixmap
+	names   []string
+}
+
+func (source bundlePixmapSource) glob(pattern string) ([]string, error) {
+	var names []string
+	for _, name := range source.names {
+		matched, err := path.Match(pattern, name)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func (source bundlePixmapSource) load(name string, mmap bool) (*PackedPixmap, error) {
+	pp := source.pixmaps[name]
+	if err := pp.Check(); err != nil {
+		return nil, err
+	}
+	return pp, nil
+}
+
+// release does nothing, the pixmaps are unmapped with the bundle
+func (source bundlePixmapSource) release() error {
+	return nil
+}
@@ -0,0 +1,174 @@
+package chanim
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBundleRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chanim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	manifest := []byte(`{
+		"animations": [
+			{"name": "idle", "frameSeries": "idle", "loopMode": "pingpong",
+				"transitions": [{"destAnimation": "wave", "frameSeries": ""}]},
+			{"name": "wave", "frameSeries": "wave", "loopCount": 1, "nextAnimation": "idle"}
+		],
+		"frameSeries": [
+			{"name": "idle", "files": "idle/*", "position": {"x": 1, "y": 2},
+				"durations": {"1": "100ms"}},
+			{"name": "wave", "files": "wave/*", "mmap": true,
+				"transitions": {"0": [{"destAnimation": "idle"}]}}
+		]
+	}`)
+	pixmaps := map[string]*PackedPixmap{
+		"idle/frame1":  makeSolidPackedPixmap(2, 1, 1),
+		"idle/frame2":  makeSolidPackedPixmap(2, 1, 2),
+		"idle/frame10": makeSolidPackedPixmap(2, 1, 10),
+		"wave/frame1":  makeSolidPackedPixmap(3, 2, 0xffff),
+	}
+
+	fileName := filepath.Join(dir, "character.bundle")
+	if err := WriteBundle(fileName, manifest, pixmaps); err != nil {
+		t.Fatal(err)
+	}
+
+	bundle, err := OpenBundle(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bundle.Close()
+
+	expectedAnimations := Animations{
+		{
+			Name:            "idle",
+			FrameSeriesName: "idle",
+			LoopMode:        LoopPingPong,
+			Transitions:     []Transition{{DestAnimationName: "wave"}},
+		},
+		{Name: "wave", FrameSeriesName: "wave", LoopCount: 1, NextAnimationName: "idle"},
+	}
+	if !reflect.DeepEqual(bundle.Animations, expectedAnimations) {
+		t.Fatalf("Animations %+v, expected %+v", bundle.Animations, expectedAnimations)
+	}
+
+	tests := []struct {
+		frameSeriesName string
+		pixmapNames     []string
+		durations       []time.Duration
+		transitions     [][]Transition
+	}{
+		{
+			frameSeriesName: "idle",
+			pixmapNames:     []string{"idle/frame1", "idle/frame2", "idle/frame10"},
+			durations:       []time.Duration{0, 100 * time.Millisecond, 0},
+			transitions:     [][]Transition{nil, nil, nil},
+		},
+		{
+			frameSeriesName: "wave",
+			pixmapNames:     []string{"wave/frame1"},
+			durations:       []time.Duration{0},
+			transitions:     [][]Transition{{{DestAnimationName: "idle"}}},
+		},
+	}
+
+	if len(bundle.AllFrameSeries) != len(tests) {
+		t.Fatalf("%v frame series, expected %v", len(bundle.AllFrameSeries), len(tests))
+	}
+	for i, tt := range tests {
+		frameSeries := bundle.AllFrameSeries[i]
+		if frameSeries.Name != tt.frameSeriesName {
+			t.Fatalf("Frame series '%s', expected '%s'", frameSeries.Name, tt.frameSeriesName)
+		}
+		if len(frameSeries.Frames) != len(tt.pixmapNames) {
+			t.Fatalf("%s: %v frames, expected %v", tt.frameSeriesName, len(frameSeries.Frames), len(tt.pixmapNames))
+		}
+
+		for j, frame := range frameSeries.Frames {
+			operation, ok := frame.DrawOperations[0].(*drawPackedPixmapOperation)
+			if !ok || len(frame.DrawOperations) != 1 {
+				t.Fatalf("%s: frame %v: unexpected draw operations", tt.frameSeriesName, j)
+			}
+			if !reflect.DeepEqual(operation.pixmap, pixmaps[tt.pixmapNames[j]]) {
+				t.Errorf("%s: frame %v: pixmap %+v, expected '%s'",
+					tt.frameSeriesName, j, operation.pixmap, tt.pixmapNames[j])
+			}
+			if frame.Duration != tt.durations[j] {
+				t.Errorf("%s: frame %v: duration %v, expected %v", tt.frameSeriesName, j, frame.Duration, tt.durations[j])
+			}
+			if !reflect.DeepEqual(frame.Transitions, tt.transitions[j]) {
+				t.Errorf("%s: frame %v: transitions %v, expected %v",
+					tt.frameSeriesName, j, frame.Transitions, tt.transitions[j])
+			}
+		}
+	}
+
+	if operation := bundle.AllFrameSeries[0].Frames[0].DrawOperations[0].(*drawPackedPixmapOperation); operation.top.X != 1 ||
+		operation.top.Y != 2 {
+		t.Errorf("Frame position %v", operation.top)
+	}
+
+	if err := bundle.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := bundle.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenInvalidBundle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chanim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fileName := filepath.Join(dir, "character.bundle")
+	manifest := []byte(`{"animations": [{"name": "idle", "frameSeries": "idle"}],
+		"frameSeries": [{"name": "idle", "files": "idle/*"}]}`)
+	pixmaps := map[string]*PackedPixmap{"idle/frame1": makeSolidPackedPixmap(2, 1, 1)}
+	if err := WriteBundle(fileName, manifest, pixmaps); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	badMagic := append([]byte(nil), data...)
+	badMagic[0] = 'X'
+	badManifest := append([]byte(nil), data...)
+	badManifest[8] = 0xff
+	badPixmap := append([]byte(nil), data...)
+	badPixmap[len(badPixmap)-1] = 1
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"bad magic", badMagic},
+		{"bad manifest size", badManifest},
+		{"truncated index", data[:len(data)-len(pixmaps["idle/frame1"].Data)-4]},
+		{"truncated data", data[:len(data)-1]},
+		{"bad pixmap", badPixmap},
+	}
+
+	for _, tt := range tests {
+		if err := ioutil.WriteFile(fileName, tt.data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if bundle, err := OpenBundle(fileName); err == nil {
+			bundle.Close()
+			t.Errorf("%s: no error", tt.name)
+		}
+	}
+}
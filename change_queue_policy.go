@@ -0,0 +1,15 @@
+package chanim
+
+// ChangeQueuePolicy defines how the animator handles animation changes
+// requested while another change is in progress
+type ChangeQueuePolicy int
+
+const (
+	// RejectChanges rejects new changes with ErrAnimationChangeRejected
+	RejectChanges ChangeQueuePolicy = iota
+	// QueueChanges queues new changes and performs them in FIFO order
+	QueueChanges
+	// CoalesceChanges keeps only the latest queued change.
+	// The replaced changes fail with ErrAnimationChangeSuperseded.
+	CoalesceChanges
+)
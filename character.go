@@ -0,0 +1,308 @@
+package chanim
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Character contains the animations and frame series of a character
+type Character struct {
+	Animations     Animations
+	AllFrameSeries []FrameSeries
+	source         pixmapSource
+}
+
+// Close unmaps the pixmaps mapped by LoadCharacter,
+// so the frames must not be used after Close.
+func (character *Character) Close() error {
+	if character.source == nil {
+		return nil
+	}
+	return character.source.release()
+}
+
+type manifestPoint struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type manifestTransition struct {
+	DestAnimation string `json:"destAnimation"`
+	FrameSeries   string `json:"frameSeries"`
+}
+
+type manifestVariation struct {
+	FrameSeries string `json:"frameSeries"`
+	Weight      int    `json:"weight"`
+}
+
+type manifestAnimation struct {
+	Name                 string               `json:"name"`
+	FrameSeries          string               `json:"frameSeries"`
+	Variations           []manifestVariation  `json:"variations"`
+	FrameRate            int                  `json:"frameRate"`
+	LoopMode             string               `json:"loopMode"`
+	RandomStart          bool                 `json:"randomStart"`
+	LoopCount            int                  `json:"loopCount"`
+	NextAnimation        string               `json:"nextAnimation"`
+	InterruptFrameSeries string               `json:"interruptFrameSeries"`
+	Transitions          []manifestTransition `json:"transitions"`
+}
+
+type manifestFrameSeries struct {
+	Name        string                          `json:"name"`
+	Files       string                          `json:"files"`
+	MMap        bool                            `json:"mmap"`
+	FrameRate   int                             `json:"frameRate"`
+	Position    manifestPoint                   `json:"position"`
+	Positions   []manifestPoint                 `json:"positions"`
+	Durations   map[string]string               `json:"durations"`
+	Transitions map[string][]manifestTransition `json:"transitions"`
+}
+
+type manifest struct {
+	Animations  []manifestAnimation   `json:"animations"`
+	FrameSeries []manifestFrameSeries `json:"frameSeries"`
+}
+
+// pixmapSource finds and loads the packed pixmaps referenced by a manifest
+type pixmapSource interface {
+	glob(pattern string) ([]string, error)
+	load(name string, mmap bool) (*PackedPixmap, error)
+	// release releases the loaded pixmaps
+	release() error
+}
+
+type dirPixmapSource struct {
+	dir    string
+	mapped []*PackedPixmap
+}
+
+func (source *dirPixmapSource) glob(pattern string) ([]string, error) {
+	fileNames, err := filepath.Glob(filepath.Join(source.dir, pattern))
+	if err != nil {
+		return nil, err
+	}
+	sortNaturally(fileNames)
+	return fileNames, nil
+}
+
+func (source *dirPixmapSource) load(name string, mmap bool) (*PackedPixmap, error) {
+	pp, err := loadFramePixmap(name, mmap)
+	if err == nil && mmap {
+		source.mapped = append(source.mapped, pp)
+	}
+	return pp, err
+}
+
+// release unmaps as many pixmaps as it can and returns the first error
+func (source *dirPixmapSource) release() error {
+	var firstErr error
+	for _, pp := range source.mapped {
+		if err := unmapPackedPixmap(pp); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	source.mapped = nil
+	return firstErr
+}
+
+// LoadCharacter loads a character from the manifest file.
+// The manifest is a JSON document:
+//
+//	{
+//		"animations": [
+//			{"name": "idle", "frameSeries": "idle", "frameRate": 12, "loopMode": "pingpong"},
+//			{"name": "wave", "frameSeries": "wave", "loopCount": 1, "nextAnimation": "idle",
+//				"transitions": [{"destAnimation": "idle", "frameSeries": "wave2idle"}]}
+//		],
+//		"frameSeries": [
+//			{
+//				"name": "idle",
+//				"files": "idle/*.ppixmap",
+//				"position": {"x": 0, "y": 0},
+//				"positions": [{"x": 0, "y": 0}, {"x": 2, "y": 0}],
+//				"durations": {"0": "500ms"},
+//				"transitions": {"10": [{"destAnimation": "wave", "frameSeries": "idle2wave"}]}
+//			}
+//		]
+//	}
+//
+// The files are globs relative to the manifest directory, the matched files are sorted
+// naturally, so frame2 goes before frame10. The frames are drawn at
+// "position" unless "positions" lists a position for every frame. The durations
+// and transitions of a frame series are keyed by frame index, the transitions
+// of an animation are available from any of its frames. The frame series with
+// "mmap" set are mapped to memory and are unmapped by Close.
+func LoadCharacter(fileName string) (*Character, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	character, err := parseCharacter(data, &dirPixmapSource{dir: filepath.Dir(fileName)})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fileName, err)
+	}
+	return character, nil
+}
+
+func parseCharacter(data []byte, source pixmapSource) (*Character, error) {
+	m := manifest{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+
+	character := &Character{source: source}
+	for _, ma := range m.Animations {
+		animation, err := ma.build()
+		if err != nil {
+			return nil, err
+		}
+		character.Animations = append(character.Animations, animation)
+	}
+
+	for _, mfs := range m.FrameSeries {
+		frameSeries, err := mfs.build(source)
+		if err != nil {
+			if releaseErr := source.release(); releaseErr != nil {
+				logrus.Warnf("Character: failed to release pixmaps: %v\n", releaseErr)
+			}
+			return nil, err
+		}
+		character.AllFrameSeries = append(character.AllFrameSeries, frameSeries)
+	}
+
+	return character, nil
+}
+
+func parseLoopMode(loopMode string) (LoopMode, error) {
+	switch strings.ToLower(loopMode) {
+	case "", "forward":
+		return LoopForward, nil
+	case "reverse":
+		return LoopReverse, nil
+	case "pingpong":
+		return LoopPingPong, nil
+	default:
+		return 0, fmt.Errorf("Unsupported loop mode '%s'", loopMode)
+	}
+}
+
+func (ma *manifestAnimation) build() (Animation, error) {
+	loopMode, err := parseLoopMode(ma.LoopMode)
+	if err != nil {
+		return Animation{}, fmt.Errorf("animation '%s': %v", ma.Name, err)
+	}
+
+	animation := Animation{
+		Name:                     ma.Name,
+		FrameSeriesName:          ma.FrameSeries,
+		FrameRate:                ma.FrameRate,
+		LoopMode:                 loopMode,
+		RandomStart:              ma.RandomStart,
+		LoopCount:                ma.LoopCount,
+		NextAnimationName:        ma.NextAnimation,
+		InterruptFrameSeriesName: ma.InterruptFrameSeries,
+	}
+	for _, mv := range ma.Variations {
+		animation.Variations = append(animation.Variations, Variation{
+			FrameSeriesName: mv.FrameSeries,
+			Weight:          mv.Weight,
+		})
+	}
+	for _, mt := range ma.Transitions {
+		animation.Transitions = append(animation.Transitions, Transition{
+			DestAnimationName: mt.DestAnimation,
+			FrameSeriesName:   mt.FrameSeries,
+		})
+	}
+	return animation, nil
+}
+
+func parseFrameIndex(key string, frameCount int) (int, error) {
+	i, err := strconv.Atoi(key)
+	if err != nil || i < 0 || i >= frameCount {
+		return 0, fmt.Errorf("Invalid frame index '%s'", key)
+	}
+	return i, nil
+}
+
+func (mfs *manifestFrameSeries) build(source pixmapSource) (FrameSeries, error) {
+	frameSeries, err := mfs.buildFrames(source)
+	if err != nil {
+		return FrameSeries{}, fmt.Errorf("frame series '%s': %v", mfs.Name, err)
+	}
+	return frameSeries, nil
+}
+
+func (mfs *manifestFrameSeries) buildFrames(source pixmapSource) (FrameSeries, error) {
+	frameSeries := FrameSeries{
+		Name:      mfs.Name,
+		FrameRate: mfs.FrameRate,
+	}
+
+	fileNames, err := source.glob(mfs.Files)
+	if err != nil {
+		return frameSeries, err
+	}
+
+	if len(mfs.Positions) != 0 && len(mfs.Positions) != len(fileNames) {
+		return frameSeries, fmt.Errorf("There are %v positions for %v frames", len(mfs.Positions), len(fileNames))
+	}
+
+	for i, fileName := range fileNames {
+		ppixmap, err := source.load(fileName, mfs.MMap)
+		if err != nil {
+			return frameSeries, fmt.Errorf("%s: %v", fileName, err)
+		}
+
+		position := mfs.Position
+		if len(mfs.Positions) != 0 {
+			position = mfs.Positions[i]
+		}
+
+		frameSeries.Frames = append(frameSeries.Frames, Frame{
+			DrawOperations: []DrawOperation{
+				NewDrawPackedPixmapOperation(image.Point{position.X, position.Y}, ppixmap),
+			},
+		})
+	}
+
+	for key, value := range mfs.Durations {
+		i, err := parseFrameIndex(key, len(frameSeries.Frames))
+		if err != nil {
+			return frameSeries, err
+		}
+
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return frameSeries, err
+		}
+		frameSeries.Frames[i].Duration = duration
+	}
+
+	for key, transitions := range mfs.Transitions {
+		i, err := parseFrameIndex(key, len(frameSeries.Frames))
+		if err != nil {
+			return frameSeries, err
+		}
+
+		for _, mt := range transitions {
+			frameSeries.Frames[i].Transitions = append(frameSeries.Frames[i].Transitions, Transition{
+				DestAnimationName: mt.DestAnimation,
+				FrameSeriesName:   mt.FrameSeries,
+			})
+		}
+	}
+
+	return frameSeries, nil
+}
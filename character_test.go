@@ -0,0 +1,159 @@
+package chanim
+
+import (
+	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testCharacterManifest = `{
+	"animations": [
+		{"name": "idle", "frameSeries": "idle", "frameRate": 12, "loopMode": "pingpong",
+			"variations": [{"frameSeries": "wave", "weight": 2}]},
+		{"name": "wave", "frameSeries": "wave", "loopCount": 1, "nextAnimation": "idle",
+			"transitions": [{"destAnimation": "idle", "frameSeries": "idle"}]}
+	],
+	"frameSeries": [
+		{
+			"name": "idle",
+			"files": "idle/*.ppixmap",
+			"mmap": true,
+			"positions": [{"x": 1, "y": 2}, {"x": 3, "y": 4}, {"x": 5, "y": 6}],
+			"durations": {"1": "500ms"},
+			"transitions": {"2": [{"destAnimation": "wave", "frameSeries": "wave"}]}
+		},
+		{"name": "wave", "files": "wave/*.ppixmap", "position": {"x": 7, "y": 8}}
+	]
+}`
+
+func writeCharacter(t *testing.T, dir string, manifest string) string {
+	writeFrameFiles(t, filepath.Join(dir, "idle"), map[string]uint16{
+		"frame10.ppixmap": 10,
+		"frame2.ppixmap":  2,
+		"frame1.ppixmap":  1,
+	})
+	writeFrameFiles(t, filepath.Join(dir, "wave"), map[string]uint16{"frame0.ppixmap": 5})
+
+	fileName := filepath.Join(dir, "character.json")
+	if err := ioutil.WriteFile(fileName, []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return fileName
+}
+
+func TestLoadCharacter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chanim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	character, err := LoadCharacter(writeCharacter(t, dir, testCharacterManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedAnimations := Animations{
+		{
+			Name:            "idle",
+			FrameSeriesName: "idle",
+			FrameRate:       12,
+			LoopMode:        LoopPingPong,
+			Variations:      []Variation{{FrameSeriesName: "wave", Weight: 2}},
+		},
+		{
+			Name:              "wave",
+			FrameSeriesName:   "wave",
+			LoopCount:         1,
+			NextAnimationName: "idle",
+			Transitions:       []Transition{{DestAnimationName: "idle", FrameSeriesName: "idle"}},
+		},
+	}
+	if !reflect.DeepEqual(character.Animations, expectedAnimations) {
+		t.Errorf("Animations %+v, expected %+v", character.Animations, expectedAnimations)
+	}
+
+	expectedColors := map[string][]uint16{"idle": {1, 2, 10}, "wave": {5}}
+	if colors := frameSeriesColors(character.AllFrameSeries); !reflect.DeepEqual(colors, expectedColors) {
+		t.Errorf("Frame series %v, expected %v", colors, expectedColors)
+	}
+
+	idle := character.AllFrameSeries[0]
+	for i, position := range []image.Point{{1, 2}, {3, 4}, {5, 6}} {
+		if op := idle.Frames[i].DrawOperations[0].(*drawPackedPixmapOperation); op.top != position {
+			t.Errorf("Position of idle frame %v: %v, expected %v", i, op.top, position)
+		}
+	}
+	if op := character.AllFrameSeries[1].Frames[0].DrawOperations[0].(*drawPackedPixmapOperation); op.top != image.Pt(7, 8) {
+		t.Errorf("Position of wave frame: %v", op.top)
+	}
+	if idle.Frames[1].Duration != 500*time.Millisecond || idle.Frames[0].Duration != 0 {
+		t.Errorf("Durations of idle frames: %v, %v", idle.Frames[0].Duration, idle.Frames[1].Duration)
+	}
+	expectedTransitions := []Transition{{DestAnimationName: "wave", FrameSeriesName: "wave"}}
+	if !reflect.DeepEqual(idle.Frames[2].Transitions, expectedTransitions) {
+		t.Errorf("Transitions of idle frame 2: %v", idle.Frames[2].Transitions)
+	}
+
+	if err := character.Close(); err != nil {
+		t.Fatalf("Could't close the character: %v", err)
+	}
+	if err := character.Close(); err != nil {
+		t.Fatalf("Could't close the character twice: %v", err)
+	}
+}
+
+func TestLoadCharacterErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+	}{
+		{"invalid JSON", `{`},
+		{"loop mode", `{"animations": [{"name": "idle", "loopMode": "backward"}]}`},
+		{"positions", `{"frameSeries": [{"name": "idle", "files": "idle/*.ppixmap", "positions": [{"x": 0, "y": 0}]}]}`},
+		{"duration index", `{"frameSeries": [{"name": "idle", "files": "idle/*.ppixmap", "durations": {"3": "1s"}}]}`},
+		{"duration", `{"frameSeries": [{"name": "idle", "files": "idle/*.ppixmap", "durations": {"0": "1"}}]}`},
+		{"transition index", `{"frameSeries": [{"name": "idle", "files": "idle/*.ppixmap",
+			"transitions": {"x": [{"destAnimation": "wave", "frameSeries": "wave"}]}}]}`},
+		{"pattern", `{"frameSeries": [{"name": "idle", "files": "idle/[.ppixmap"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "chanim")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			if _, err := LoadCharacter(writeCharacter(t, dir, tt.manifest)); err == nil {
+				t.Fatal("Invalid manifest is loaded")
+			}
+		})
+	}
+}
+
+func TestParseCharacterReleasesMappedPixmaps(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chanim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeCharacter(t, dir, "")
+	manifest := `{"frameSeries": [
+		{"name": "idle", "files": "idle/*.ppixmap", "mmap": true},
+		{"name": "wave", "files": "wave/*.ppixmap", "durations": {"1": "1s"}}
+	]}`
+	source := &dirPixmapSource{dir: dir}
+	if _, err := parseCharacter([]byte(manifest), source); err == nil {
+		t.Fatal("Invalid manifest is loaded")
+	}
+	if len(source.mapped) != 0 {
+		t.Fatalf("%v pixmaps are not unmapped", len(source.mapped))
+	}
+}
@@ -0,0 +1,168 @@
+package chanim
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the time source of Animator
+type Clock interface {
+	Now() time.Time
+	// Sleep blocks for d or until a value is received from wake.
+	// It returns true if the sleep is interrupted by wake.
+	Sleep(d time.Duration, wake <-chan struct{}) bool
+}
+
+type realClock struct {
+}
+
+// RealClock returns the clock based on the system time
+func RealClock() Clock {
+	return realClock{}
+}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) Sleep(d time.Duration, wake <-chan struct{}) bool {
+	if d <= 0 {
+		return false
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return false
+	case <-wake:
+		return true
+	}
+}
+
+type manualSleeper struct {
+	wakeTime time.Time
+	done     chan struct{}
+}
+
+// ManualClock is a clock which time is advanced manually.
+// It makes the animator timing deterministic in tests and offline rendering.
+type ManualClock struct {
+	mutex       sync.Mutex
+	cond        *sync.Cond
+	now         time.Time
+	sleepers    []*manualSleeper
+	autoAdvance bool
+}
+
+// NewManualClock creates ManualClock
+func NewManualClock(now time.Time) *ManualClock {
+	clock := &ManualClock{
+		now: now,
+	}
+	clock.cond = sync.NewCond(&clock.mutex)
+	return clock
+}
+
+// Now gets the current clock time
+func (clock *ManualClock) Now() time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	return clock.now
+}
+
+// Sleep blocks until the clock is advanced by d or a value is received from wake.
+// In the auto advance mode it advances the clock by d and returns immediately.
+func (clock *ManualClock) Sleep(d time.Duration, wake <-chan struct{}) bool {
+	clock.mutex.Lock()
+	if d <= 0 {
+		clock.mutex.Unlock()
+		return false
+	}
+
+	if clock.autoAdvance {
+		clock.setNow(clock.now.Add(d))
+		clock.mutex.Unlock()
+		return false
+	}
+
+	sleeper := &manualSleeper{
+		wakeTime: clock.now.Add(d),
+		done:     make(chan struct{}),
+	}
+	clock.sleepers = append(clock.sleepers, sleeper)
+	clock.cond.Broadcast()
+	clock.mutex.Unlock()
+
+	select {
+	case <-sleeper.done:
+		return false
+	case <-wake:
+		clock.mutex.Lock()
+		clock.removeSleeper(sleeper)
+		clock.mutex.Unlock()
+		return true
+	}
+}
+
+// Advance advances the clock by d and wakes up the sleepers whose time has come
+func (clock *ManualClock) Advance(d time.Duration) {
+	clock.mutex.Lock()
+	clock.setNow(clock.now.Add(d))
+	clock.mutex.Unlock()
+}
+
+// SetAutoAdvance enables or disables the auto advance mode.
+// Enabling it advances the clock to wake up the goroutines sleeping on the clock.
+func (clock *ManualClock) SetAutoAdvance(autoAdvance bool) {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	clock.autoAdvance = autoAdvance
+	if !autoAdvance {
+		return
+	}
+
+	now := clock.now
+	for _, sleeper := range clock.sleepers {
+		if sleeper.wakeTime.After(now) {
+			now = sleeper.wakeTime
+		}
+	}
+	clock.setNow(now)
+}
+
+// WaitForSleepers blocks until at least sleeperCount goroutines sleep on the clock
+func (clock *ManualClock) WaitForSleepers(sleeperCount int) {
+	clock.mutex.Lock()
+	for len(clock.sleepers) < sleeperCount {
+		clock.cond.Wait()
+	}
+	clock.mutex.Unlock()
+}
+
+func (clock *ManualClock) setNow(now time.Time) {
+	clock.now = now
+
+	sleepers := clock.sleepers[:0]
+	for _, sleeper := range clock.sleepers {
+		if now.Before(sleeper.wakeTime) {
+			sleepers = append(sleepers, sleeper)
+		} else {
+			close(sleeper.done)
+		}
+	}
+	clock.sleepers = sleepers
+	clock.cond.Broadcast()
+}
+
+func (clock *ManualClock) removeSleeper(sleeper *manualSleeper) {
+	for i, s := range clock.sleepers {
+		if s == sleeper {
+			clock.sleepers = append(clock.sleepers[:i], clock.sleepers[i+1:]...)
+			clock.cond.Broadcast()
+			return
+		}
+	}
+}
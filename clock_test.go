@@ -0,0 +1,87 @@
+package chanim
+
+import (
+	"testing"
+	"time"
+)
+
+func TestManualClockSleep(t *testing.T) {
+	start := time.Unix(0, 0)
+	clock := NewManualClock(start)
+
+	woken := make(chan bool)
+	go func() {
+		woken <- clock.Sleep(100*time.Millisecond, nil)
+	}()
+
+	clock.WaitForSleepers(1)
+	clock.Advance(99 * time.Millisecond)
+	select {
+	case <-woken:
+		t.Fatal("Sleep returns before the clock is advanced by the duration")
+	default:
+	}
+
+	clock.Advance(time.Millisecond)
+	if interrupted := <-woken; interrupted {
+		t.Fatal("Sleep is interrupted")
+	}
+	if now := clock.Now(); !now.Equal(start.Add(100 * time.Millisecond)) {
+		t.Fatalf("Clock time %v", now)
+	}
+	if clock.Sleep(0, nil) {
+		t.Fatal("Zero sleep is interrupted")
+	}
+}
+
+func TestManualClockSleepWake(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	wake := make(chan struct{}, 1)
+
+	woken := make(chan bool)
+	go func() {
+		woken <- clock.Sleep(time.Hour, wake)
+	}()
+
+	clock.WaitForSleepers(1)
+	wake <- struct{}{}
+	if interrupted := <-woken; !interrupted {
+		t.Fatal("Sleep isn't interrupted by wake")
+	}
+
+	// The interrupted sleeper is removed, so advancing the clock doesn't touch it
+	clock.Advance(time.Hour)
+}
+
+func TestManualClockAutoAdvance(t *testing.T) {
+	start := time.Unix(0, 0)
+	clock := NewManualClock(start)
+
+	woken := make(chan bool)
+	go func() {
+		woken <- clock.Sleep(time.Hour, nil)
+	}()
+
+	// Enabling the auto advance mode wakes up the sleeping goroutines
+	clock.WaitForSleepers(1)
+	clock.SetAutoAdvance(true)
+	if interrupted := <-woken; interrupted {
+		t.Fatal("Sleep is interrupted")
+	}
+
+	clock.Sleep(time.Minute, nil)
+	if now := clock.Now(); !now.Equal(start.Add(time.Hour + time.Minute)) {
+		t.Fatalf("Clock time %v", now)
+	}
+}
+
+func TestRealClockSleepWake(t *testing.T) {
+	wake := make(chan struct{}, 1)
+	wake <- struct{}{}
+	if !RealClock().Sleep(time.Hour, wake) {
+		t.Fatal("Sleep isn't interrupted by wake")
+	}
+	if RealClock().Sleep(time.Millisecond, wake) {
+		t.Fatal("Sleep is interrupted")
+	}
+}
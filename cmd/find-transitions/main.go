@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/rmcsoft/chanim"
+)
+
+type options struct {
+	Manifest  string  `short:"m" long:"manifest"  required:"true" description:"The character manifest"`
+	Width     int     `short:"W" long:"width"     required:"true" description:"The screen width"`
+	Height    int     `short:"H" long:"height"    required:"true" description:"The screen height"`
+	Threshold float64 `short:"t" long:"threshold" default:"0.01"  description:"The maximum mean difference of pixels in range [0, 1]"`
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	os.Exit(1)
+}
+
+func parseCmd() options {
+	var opts options
+	var cmdParser = flags.NewParser(&opts, flags.HelpFlag|flags.PassDoubleDash)
+
+	if _, err := cmdParser.Parse(); err != nil {
+		if flagsErr, ok := err.(*flags.Error); ok && flagsErr.Type == flags.ErrHelp {
+			fmt.Println(flagsErr)
+			os.Exit(0)
+		}
+
+		fail(err)
+	}
+
+	return opts
+}
+
+func main() {
+	opts := parseCmd()
+
+	character, err := chanim.LoadCharacter(opts.Manifest)
+	if err != nil {
+		fail(err)
+	}
+
+	report, err := chanim.DiscoverTransitions(character.Animations, character.AllFrameSeries,
+		chanim.TransitionDiscoveryOptions{
+			Width:     opts.Width,
+			Height:    opts.Height,
+			PixFormat: chanim.RGB16,
+			Threshold: opts.Threshold,
+		})
+	if err != nil {
+		fail(err)
+	}
+
+	for _, transition := range report.Transitions {
+		fmt.Printf("%s[%d] -> %s (difference=%.4f)\n", transition.FrameSeriesName, transition.FrameNum,
+			transition.DestAnimationName, transition.Difference)
+	}
+
+	fmt.Printf("---------------------------\n")
+	for _, wait := range report.Waits {
+		if wait.Frames < 0 {
+			fmt.Printf("%s -> %s: no transition\n", wait.From, wait.To)
+		} else {
+			fmt.Printf("%s -> %s: worst-case wait=%d frames\n", wait.From, wait.To, wait.Frames)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -14,7 +15,9 @@ import (
 
 type options struct {
 	InputDir  string `short:"i" long:"input-dir"  required:"true" description:"The input directory"`
-	OutputDir string `short:"o" long:"output-dir" required:"true" description:"The output directory"`
+	OutputDir string `short:"o" long:"output-dir" description:"The output directory"`
+	Bundle    string `short:"b" long:"bundle"     description:"The output bundle file"`
+	Manifest  string `short:"m" long:"manifest"   description:"The character manifest written to the bundle"`
 
 	NotRotate      bool `short:"n" long:"not-rotate"       description:"Disable image rotate"`
 	ClearOutputDir bool `short:"c" long:"clear-output-dir" description:"Clears the output directory."`
@@ -65,6 +68,17 @@ func parseCmd() options {
 		fail(err)
 	}
 
+	if opts.Bundle != "" {
+		if opts.Manifest == "" {
+			fail(errors.New("The manifest is required to write a bundle"))
+		}
+		return opts
+	}
+
+	if opts.OutputDir == "" {
+		fail(errors.New("The output directory or the bundle is required"))
+	}
+
 	if opts.OutputDir, err = filepath.Abs(opts.OutputDir); err != nil {
 		fail(err)
 	}
@@ -109,21 +123,25 @@ func rotatePixmap(pixmap *chanim.Pixmap) *chanim.Pixmap {
 	}
 }
 
-func savePackedPixmap(opts *options, inputImageFile string, packedPixmap *chanim.PackedPixmap) {
+func getRelOutputPath(opts *options, inputImageFile string) string {
 	relInputPath, err := filepath.Rel(opts.InputDir, inputImageFile)
 	if err != nil {
 		fail(err)
 	}
-	relImageDir := filepath.Dir(relInputPath)
 
-	outputImageDir := filepath.Join(opts.OutputDir, relImageDir)
-	err = os.MkdirAll(outputImageDir, 0755)
+	inputImageExt := filepath.Ext(inputImageFile)
+	return strings.TrimSuffix(relInputPath, inputImageExt) + ".ppixmap"
+}
+
+func savePackedPixmap(opts *options, inputImageFile string, packedPixmap *chanim.PackedPixmap) {
+	relOutputPath := getRelOutputPath(opts, inputImageFile)
+
+	outputImageDir := filepath.Join(opts.OutputDir, filepath.Dir(relOutputPath))
+	err := os.MkdirAll(outputImageDir, 0755)
 	if err != nil {
 		fail(err)
 	}
 
-	inputImageExt := filepath.Ext(inputImageFile)
-	relOutputPath := strings.TrimSuffix(relInputPath, inputImageExt) + ".ppixmap"
 	outputFile := filepath.Join(opts.OutputDir, relOutputPath)
 	err = packedPixmap.Save(outputFile)
 	if err != nil {
@@ -131,6 +149,21 @@ func savePackedPixmap(opts *options, inputImageFile string, packedPixmap *chanim
 	}
 }
 
+// saveBundle writes the packed pixmaps and the manifest to the bundle file.
+// The pixmaps are named by their paths in the output directory,
+// so the globs of the manifest are relative to the input directory.
+func saveBundle(opts *options, packedPixmaps map[string]*chanim.PackedPixmap) {
+	manifest, err := ioutil.ReadFile(opts.Manifest)
+	if err != nil {
+		fail(err)
+	}
+
+	err = chanim.WriteBundle(opts.Bundle, manifest, packedPixmaps)
+	if err != nil {
+		fail(err)
+	}
+}
+
 func clearDir(dir string) {
 	files, err := ioutil.ReadDir(dir)
 	if err != nil {
@@ -151,10 +184,12 @@ func clearDir(dir string) {
 func main() {
 	opts := parseCmd()
 
-	if opts.ClearOutputDir {
+	if opts.ClearOutputDir && opts.Bundle == "" {
 		clearDir(opts.OutputDir)
 	}
 
+	packedPixmaps := make(map[string]*chanim.PackedPixmap)
+
 	var imageCount int
 	var packedSize int64
 	var unpackedSize int64
@@ -176,10 +211,18 @@ func main() {
 		}
 		packedSize += int64(len(packedPixmap.Data))
 
-		savePackedPixmap(&opts, imageFile, packedPixmap)
+		if opts.Bundle != "" {
+			packedPixmaps[filepath.ToSlash(getRelOutputPath(&opts, imageFile))] = packedPixmap
+		} else {
+			savePackedPixmap(&opts, imageFile, packedPixmap)
+		}
 		imageCount++
 	}
 
+	if opts.Bundle != "" {
+		saveBundle(&opts, packedPixmaps)
+	}
+
 	fmt.Printf("---------------------------\n")
 	fmt.Printf("Processed files=%v\n", imageCount)
 	fmt.Printf("unpackedSize=%vM\n", float32(unpackedSize)/float32(1024*1024))
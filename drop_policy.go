@@ -0,0 +1,87 @@
+package chanim
+
+import (
+	"fmt"
+	"time"
+)
+
+// DropMode defines what the animator does with frames that are behind schedule
+type DropMode int
+
+const (
+	// DropLateFrames drops late frames to catch up with the schedule
+	DropLateFrames DropMode = iota
+	// NeverDropFrames draws late frames and lets the schedule slip
+	NeverDropFrames
+	// ResetScheduleWhenLate draws late frames trying to catch up with the schedule.
+	// The schedule is re-based after LateFrameLimit late frames in a row.
+	ResetScheduleWhenLate
+	// KeepTransitionFrames drops late frames except the frames of transition series
+	KeepTransitionFrames
+)
+
+func (mode DropMode) String() string {
+	switch mode {
+	case DropLateFrames:
+		return "DropLateFrames"
+	case NeverDropFrames:
+		return "NeverDropFrames"
+	case ResetScheduleWhenLate:
+		return "ResetScheduleWhenLate"
+	case KeepTransitionFrames:
+		return "KeepTransitionFrames"
+	default:
+		return "Unknown"
+	}
+}
+
+// DropPolicy defines how the animator catches up when it falls behind schedule
+type DropPolicy struct {
+	Mode DropMode
+
+	// LateFrameLimit is the number of late frames in a row after which
+	// ResetScheduleWhenLate re-bases the schedule. It must be at least 1.
+	LateFrameLimit int
+}
+
+func (policy DropPolicy) String() string {
+	if policy.Mode == ResetScheduleWhenLate {
+		return fmt.Sprintf("%v(%v)", policy.Mode, policy.LateFrameLimit)
+	}
+	return policy.Mode.String()
+}
+
+// frameSchedule is the schedule of frames shown by the animator
+type frameSchedule struct {
+	// nextFrameTime is the time the next frame is shown
+	nextFrameTime  time.Time
+	lateFrameCount int
+}
+
+// add adds the frame to the schedule and returns the time the frame should be shown.
+// If the frame is behind schedule, the drop policy is applied, false is returned
+// for dropped frames.
+func (schedule *frameSchedule) add(next scheduledFrame, now time.Time, policy DropPolicy) (time.Time, bool) {
+	showFrameTime := schedule.nextFrameTime
+	schedule.nextFrameTime = schedule.nextFrameTime.Add(next.duration)
+	if schedule.nextFrameTime.After(now) {
+		schedule.lateFrameCount = 0
+		return showFrameTime, true
+	}
+
+	switch policy.Mode {
+	case NeverDropFrames:
+		schedule.nextFrameTime = now.Add(next.duration)
+	case ResetScheduleWhenLate:
+		schedule.lateFrameCount++
+		if schedule.lateFrameCount >= policy.LateFrameLimit {
+			schedule.lateFrameCount = 0
+			schedule.nextFrameTime = now.Add(next.duration)
+		}
+	case KeepTransitionFrames:
+		return showFrameTime, next.isTransition
+	default:
+		return showFrameTime, false
+	}
+	return showFrameTime, true
+}
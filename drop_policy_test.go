@@ -0,0 +1,72 @@
+package chanim
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFrameScheduleAdd(t *testing.T) {
+	start := time.Unix(0, 0)
+	late := start.Add(100 * time.Millisecond)
+	resetPolicy := DropPolicy{Mode: ResetScheduleWhenLate, LateFrameLimit: 2}
+
+	tests := []struct {
+		name           string
+		policy         DropPolicy
+		isTransition   bool
+		lateFrameCount int
+		now            time.Time
+		ok             bool
+		nextFrameTime  time.Time
+	}{
+		{"in time", DropPolicy{Mode: DropLateFrames}, false, 0, start, true, start.Add(40 * time.Millisecond)},
+		{"drop", DropPolicy{Mode: DropLateFrames}, false, 0, late, false, start.Add(40 * time.Millisecond)},
+		{"never drop", DropPolicy{Mode: NeverDropFrames}, false, 0, late, true, late.Add(40 * time.Millisecond)},
+		{"late frame", resetPolicy, false, 0, late, true, start.Add(40 * time.Millisecond)},
+		{"reset", resetPolicy, false, 1, late, true, late.Add(40 * time.Millisecond)},
+		{"keep transition frame", DropPolicy{Mode: KeepTransitionFrames}, true, 0, late, true, start.Add(40 * time.Millisecond)},
+		{"drop animation frame", DropPolicy{Mode: KeepTransitionFrames}, false, 0, late, false, start.Add(40 * time.Millisecond)},
+	}
+
+	for _, tt := range tests {
+		schedule := frameSchedule{nextFrameTime: start, lateFrameCount: tt.lateFrameCount}
+		next := scheduledFrame{duration: 40 * time.Millisecond, isTransition: tt.isTransition}
+		showFrameTime, ok := schedule.add(next, tt.now, tt.policy)
+		if ok != tt.ok || !showFrameTime.Equal(start) || !schedule.nextFrameTime.Equal(tt.nextFrameTime) {
+			t.Errorf("%s: ok %v, show time %v, next frame time %v", tt.name, ok, showFrameTime, schedule.nextFrameTime)
+		}
+	}
+}
+
+func TestDropLateFrames(t *testing.T) {
+	tests := []struct {
+		policy        DropPolicy
+		frames        []string
+		framesDropped int
+	}{
+		{DropPolicy{Mode: DropLateFrames}, []string{"a0", "a0"}, 2},
+		{DropPolicy{Mode: NeverDropFrames}, []string{"a0", "a1"}, 0},
+	}
+
+	for _, tt := range tests {
+		test := startAnimatorTest(t, Animations{{Name: "A", FrameSeriesName: "a"}}, []FrameSeries{makeFrameSeries("a", 3)},
+			"A", WithDropPolicy(tt.policy))
+		test.advance(120 * time.Millisecond)
+		test.checkFrames(tt.frames...)
+		test.animator.Stop()
+
+		if stats := test.animator.Stats(); stats.FramesDropped != tt.framesDropped {
+			t.Errorf("%v: %v frames dropped, expected %v", tt.policy, stats.FramesDropped, tt.framesDropped)
+		}
+		test.close()
+	}
+}
+
+func TestInvalidLateFrameLimit(t *testing.T) {
+	policy := DropPolicy{Mode: ResetScheduleWhenLate}
+	_, err := NewAnimator(NullPaintEngine(), Animations{{Name: "A", FrameSeriesName: "a"}},
+		[]FrameSeries{makeFrameSeries("a", 1)}, WithDropPolicy(policy))
+	if err == nil {
+		t.Fatal("Late frame limit 0 is accepted")
+	}
+}
@@ -0,0 +1,41 @@
+package chanim
+
+// ErrorAction is the action the animator takes when a frame can't be drawn
+type ErrorAction int
+
+const (
+	// SkipFrameOnError skips the frame and goes on with the next one
+	SkipFrameOnError ErrorAction = iota
+	// FallbackOnError cuts to the fallback animation without waiting for a transition,
+	// the pending changes are superseded
+	FallbackOnError
+	// StopOnError stops the animator
+	StopOnError
+	// RetryOnError draws the frame again. If all retries fail, the frame is skipped.
+	RetryOnError
+)
+
+func (action ErrorAction) String() string {
+	switch action {
+	case SkipFrameOnError:
+		return "SkipFrame"
+	case FallbackOnError:
+		return "Fallback"
+	case StopOnError:
+		return "Stop"
+	case RetryOnError:
+		return "Retry"
+	default:
+		return "Unknown"
+	}
+}
+
+// ErrorPolicy defines how the animator handles drawing errors
+type ErrorPolicy struct {
+	Action ErrorAction
+
+	// FallbackAnimationName is the name of the animation to change to for FallbackOnError
+	FallbackAnimationName string
+	// RetryCount is the number of retries for RetryOnError
+	RetryCount int
+}
@@ -0,0 +1,120 @@
+package chanim
+
+import "sync"
+
+// EventType is the type of Animator event
+type EventType int
+
+const (
+	// EventAnimationStarted is sent when an animation starts playing
+	EventAnimationStarted EventType = iota
+	// EventTransitionStarted is sent when a transition to the next animation starts
+	EventTransitionStarted
+	// EventTransitionFinished is sent when all frames of a transition have been played
+	EventTransitionFinished
+	// EventLoopWrapped is sent when the last frame of an animation is played
+	// and the animation continues from its first frame
+	EventLoopWrapped
+	// EventChangeFailed is sent when an animation change fails
+	EventChangeFailed
+	// EventAnimationCompleted is sent when an animation has played LoopCount times
+	EventAnimationCompleted
+	// EventDrawFailed is sent when a frame can't be drawn
+	EventDrawFailed
+)
+
+func (eventType EventType) String() string {
+	switch eventType {
+	case EventAnimationStarted:
+		return "AnimationStarted"
+	case EventTransitionStarted:
+		return "TransitionStarted"
+	case EventTransitionFinished:
+		return "TransitionFinished"
+	case EventLoopWrapped:
+		return "LoopWrapped"
+	case EventChangeFailed:
+		return "ChangeFailed"
+	case EventAnimationCompleted:
+		return "AnimationCompleted"
+	case EventDrawFailed:
+		return "DrawFailed"
+	default:
+		return "Unknown"
+	}
+}
+
+// Event describes something that happened inside Animator
+type Event struct {
+	Type EventType
+
+	// AnimationName is the name of the current animation
+	AnimationName string
+	// DestAnimationName is the name of the destination animation of a transition or a change
+	DestAnimationName string
+	// FrameSeriesName is the name of the transition frame series.
+	// It is empty for transitions without frames.
+	FrameSeriesName string
+	// Err is the error of a failed change or a failed drawing
+	Err error
+}
+
+// EventListener receives Animator events
+type EventListener func(event Event)
+
+// eventSubscriber delivers events to a listener in its own goroutine,
+// so posting an event never blocks.
+type eventSubscriber struct {
+	listener EventListener
+
+	mutex    sync.Mutex
+	cond     *sync.Cond
+	events   []Event
+	isClosed bool
+}
+
+func newEventSubscriber(listener EventListener) *eventSubscriber {
+	subscriber := &eventSubscriber{
+		listener: listener,
+	}
+	subscriber.cond = sync.NewCond(&subscriber.mutex)
+	go subscriber.run()
+	return subscriber
+}
+
+func (subscriber *eventSubscriber) post(event Event) {
+	subscriber.mutex.Lock()
+	if !subscriber.isClosed {
+		subscriber.events = append(subscriber.events, event)
+		subscriber.cond.Signal()
+	}
+	subscriber.mutex.Unlock()
+}
+
+func (subscriber *eventSubscriber) close() {
+	subscriber.mutex.Lock()
+	subscriber.isClosed = true
+	subscriber.events = nil
+	subscriber.cond.Signal()
+	subscriber.mutex.Unlock()
+}
+
+func (subscriber *eventSubscriber) run() {
+	for {
+		subscriber.mutex.Lock()
+		for len(subscriber.events) == 0 && !subscriber.isClosed {
+			subscriber.cond.Wait()
+		}
+		if subscriber.isClosed {
+			subscriber.mutex.Unlock()
+			return
+		}
+		events := subscriber.events
+		subscriber.events = nil
+		subscriber.mutex.Unlock()
+
+		for _, event := range events {
+			subscriber.listener(event)
+		}
+	}
+}
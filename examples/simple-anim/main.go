@@ -1,9 +1,7 @@
 package main
 
 import (
-	"image"
 	"os"
-	"path/filepath"
 
 	"github.com/rmcsoft/chanim"
 )
@@ -13,31 +11,16 @@ const (
 )
 
 func loadFrameseries(intputDir string) chanim.FrameSeries {
-
-	inputFiles, err := filepath.Glob(filepath.Join(intputDir, "*.ppixmap"))
+	allFrameSeries, err := chanim.LoadFrameSeriesDir(intputDir, chanim.FrameSeriesLoadOptions{})
 	if err != nil {
 		panic(err)
 	}
 
-	frames := make([]chanim.Frame, 0)
-	for _, inputFile := range inputFiles {
-		ppixmap, err := chanim.LoadPackedPixmap(inputFile)
-		if err != nil {
-			panic(err)
-		}
-
-		frame := chanim.Frame{
-			DrawOperations: []chanim.DrawOperation{
-				chanim.NewDrawPackedPixmapOperation(image.Point{0, 0}, ppixmap),
-			},
-		}
-		frames = append(frames, frame)
+	if len(allFrameSeries) == 0 {
+		panic("No frames found")
 	}
 
-	return chanim.FrameSeries{
-		Name:   "TestFrameSeries",
-		Frames: frames,
-	}
+	return allFrameSeries[0]
 }
 
 func makePaintEngine() chanim.PaintEngine {
@@ -1,9 +1,15 @@
 package chanim
 
+import "time"
+
 // Frame contains a set of operations for drawing.
 type Frame struct {
 	DrawOperations []DrawOperation
 	Transitions    []Transition
+
+	// Duration is the time the frame is shown.
+	// If it is zero, the frame is shown for one period of the frame rate.
+	Duration time.Duration
 }
 
 // Draw draws a frame delta.
@@ -4,4 +4,8 @@ package chanim
 type FrameSeries struct {
 	Name   string
 	Frames []Frame
+
+	// FrameRate is the frame rate used when the series is played as a transition.
+	// If it is zero, the animator frame rate is used.
+	FrameRate int
 }
@@ -0,0 +1,156 @@
+package chanim
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// FrameLoadError is the error of loading a frame file
+type FrameLoadError struct {
+	FileName string
+	Err      error
+}
+
+func (err *FrameLoadError) Error() string {
+	return fmt.Sprintf("Could't load a frame from '%s': %v", err.FileName, err.Err)
+}
+
+// FrameLoadErrors contains the errors of all frame files that couldn't be loaded
+type FrameLoadErrors []*FrameLoadError
+
+func (errs FrameLoadErrors) Error() string {
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// FrameSeriesLoadOptions configures LoadFrameSeriesDir
+type FrameSeriesLoadOptions struct {
+	// MMap maps the pixmap files to memory instead of reading them
+	MMap bool
+	// Position is the position the frames are drawn at
+	Position image.Point
+}
+
+// LoadFrameSeriesDir loads frame series from a directory tree produced by repack.
+// Each directory containing .ppixmap files becomes a FrameSeries named after its path
+// relative to rootDir, the files in rootDir itself make a series named after rootDir.
+// An error is returned if that name is also the name of a subdirectory series.
+// The frames are sorted naturally, so frame2 goes before frame10.
+// If some files can't be loaded, FrameLoadErrors is returned and the mapped files are unmapped.
+func LoadFrameSeriesDir(rootDir string, options FrameSeriesLoadOptions) ([]FrameSeries, error) {
+	dirFiles := make(map[string][]string)
+	walkFn := func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			if isPixmap, _ := filepath.Match("*.ppixmap", info.Name()); isPixmap {
+				dir := filepath.Dir(path)
+				dirFiles[dir] = append(dirFiles[dir], path)
+			}
+		}
+		return err
+	}
+
+	if err := filepath.Walk(rootDir, walkFn); err != nil {
+		return nil, err
+	}
+
+	dirs := make([]string, 0, len(dirFiles))
+	for dir := range dirFiles {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	var errs FrameLoadErrors
+	allFrameSeries := make([]FrameSeries, 0, len(dirs))
+	release := func() {
+		if options.MMap {
+			if err := unmapFrameSeries(allFrameSeries); err != nil {
+				logrus.Warnf("LoadFrameSeriesDir: failed to unmap frames: %v\n", err)
+			}
+		}
+	}
+
+	absRootDir, err := filepath.Abs(rootDir)
+	if err != nil {
+		return nil, err
+	}
+
+	nameDirs := make(map[string]string, len(dirs))
+	for _, dir := range dirs {
+		name, err := filepath.Rel(rootDir, dir)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		if name == "." {
+			name = filepath.Base(absRootDir)
+		}
+		name = filepath.ToSlash(name)
+		if otherDir, ok := nameDirs[name]; ok {
+			release()
+			return nil, fmt.Errorf("The frame series of '%s' and '%s' have the same name '%s'", otherDir, dir, name)
+		}
+		nameDirs[name] = dir
+
+		fileNames := dirFiles[dir]
+		sortNaturally(fileNames)
+
+		frameSeries := FrameSeries{
+			Name:   name,
+			Frames: make([]Frame, 0, len(fileNames)),
+		}
+		for _, fileName := range fileNames {
+			ppixmap, err := loadFramePixmap(fileName, options.MMap)
+			if err != nil {
+				errs = append(errs, &FrameLoadError{FileName: fileName, Err: err})
+				continue
+			}
+
+			frameSeries.Frames = append(frameSeries.Frames, Frame{
+				DrawOperations: []DrawOperation{
+					NewDrawPackedPixmapOperation(options.Position, ppixmap),
+				},
+			})
+		}
+		allFrameSeries = append(allFrameSeries, frameSeries)
+	}
+
+	if len(errs) > 0 {
+		release()
+		return nil, errs
+	}
+	return allFrameSeries, nil
+}
+
+// unmapFrameSeries unmaps the pixmaps of frames loaded with mmap.
+// It unmaps as many pixmaps as it can and returns the first error.
+func unmapFrameSeries(allFrameSeries []FrameSeries) error {
+	var firstErr error
+	for _, frameSeries := range allFrameSeries {
+		for _, frame := range frameSeries.Frames {
+			for _, drawOperation := range frame.DrawOperations {
+				if op, ok := drawOperation.(*drawPackedPixmapOperation); ok {
+					if err := unmapPackedPixmap(op.pixmap); err != nil && firstErr == nil {
+						firstErr = err
+					}
+				}
+			}
+		}
+	}
+	return firstErr
+}
+
+func loadFramePixmap(fileName string, mmap bool) (*PackedPixmap, error) {
+	if mmap {
+		return MMapPackedPixmap(fileName)
+	}
+	return LoadPackedPixmap(fileName)
+}
@@ -0,0 +1,106 @@
+package chanim
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeFrameFiles(t *testing.T, dir string, colors map[string]uint16) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, color := range colors {
+		if err := makeSolidPackedPixmap(1, 1, color).Save(filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func frameSeriesColors(allFrameSeries []FrameSeries) map[string][]uint16 {
+	colors := make(map[string][]uint16)
+	for _, frameSeries := range allFrameSeries {
+		colors[frameSeries.Name] = []uint16{}
+		for _, frame := range frameSeries.Frames {
+			data := frame.DrawOperations[0].(*drawPackedPixmapOperation).pixmap.Data
+			colors[frameSeries.Name] = append(colors[frameSeries.Name], uint16(data[1])|uint16(data[2])<<8)
+		}
+	}
+	return colors
+}
+
+func TestLoadFrameSeriesDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chanim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rootDir := filepath.Join(dir, "character")
+	writeFrameFiles(t, rootDir, map[string]uint16{"frame0.ppixmap": 1, "notes.txt": 0})
+	writeFrameFiles(t, filepath.Join(rootDir, "idle"), map[string]uint16{
+		"frame10.ppixmap": 10,
+		"frame2.ppixmap":  2,
+		"frame1.ppixmap":  1,
+	})
+	writeFrameFiles(t, filepath.Join(rootDir, "wave", "up"), map[string]uint16{"frame0.ppixmap": 5})
+
+	expected := map[string][]uint16{
+		"character": {1},
+		"idle":      {1, 2, 10},
+		"wave/up":   {5},
+	}
+	for _, mmap := range []bool{false, true} {
+		// The root series is named after the directory even if rootDir ends with "."
+		allFrameSeries, err := LoadFrameSeriesDir(filepath.Join(rootDir, "."), FrameSeriesLoadOptions{MMap: mmap})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if colors := frameSeriesColors(allFrameSeries); !reflect.DeepEqual(colors, expected) {
+			t.Errorf("MMap %v: frame series %v, expected %v", mmap, colors, expected)
+		}
+		if mmap {
+			if err := unmapFrameSeries(allFrameSeries); err != nil {
+				t.Fatalf("Could't unmap the frame series: %v", err)
+			}
+		}
+	}
+}
+
+func TestLoadFrameSeriesDirNameCollision(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chanim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rootDir := filepath.Join(dir, "idle")
+	writeFrameFiles(t, rootDir, map[string]uint16{"frame0.ppixmap": 1})
+	writeFrameFiles(t, filepath.Join(rootDir, "idle"), map[string]uint16{"frame0.ppixmap": 2})
+
+	if _, err := LoadFrameSeriesDir(rootDir, FrameSeriesLoadOptions{MMap: true}); err == nil {
+		t.Fatal("Frame series with the same name are loaded")
+	}
+}
+
+func TestLoadFrameSeriesDirErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chanim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFrameFiles(t, dir, map[string]uint16{"frame0.ppixmap": 1})
+	badFileName := filepath.Join(dir, "frame1.ppixmap")
+	if err := ioutil.WriteFile(badFileName, []byte("bad"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = LoadFrameSeriesDir(dir, FrameSeriesLoadOptions{MMap: true})
+	errs, ok := err.(FrameLoadErrors)
+	if !ok || len(errs) != 1 || errs[0].FileName != badFileName {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
@@ -379,6 +379,22 @@ func (p *kmsdrmPaintEngine) End() error {
 	return err
 }
 
+// Close releases the framebuffers and closes the drm device
+func (p *kmsdrmPaintEngine) Close() error {
+	for _, fb := range p.framebuffers {
+		p.destroyFramebuffer(fb)
+	}
+	p.framebuffers = nil
+
+	if p.card == nil {
+		return nil
+	}
+
+	err := p.card.Close()
+	p.card = nil
+	return err
+}
+
 // NewKMSDRMPaintEngine creates KMSDRMPaintEngine
 func NewKMSDRMPaintEngine(cardNum int, pixFormat PixelFormat) (PaintEngine, error) {
 	card, err := drm.OpenCard(cardNum)
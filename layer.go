@@ -0,0 +1,10 @@
+package chanim
+
+import "time"
+
+// Layer is a source of frames composited by Scene
+type Layer interface {
+	// Frame gets the frame to draw at the given time.
+	// It returns nil if the layer has nothing to draw.
+	Frame(now time.Time) *Frame
+}
@@ -0,0 +1,214 @@
+package chanim
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// VisemeKey is a viseme starting at the given offset from the start of the speech
+type VisemeKey struct {
+	Offset time.Duration
+	Viseme string
+}
+
+// VisemeTimeline is a sequence of visemes ordered by offset
+type VisemeTimeline []VisemeKey
+
+// LipSync selects mouth frames from a viseme timeline. Each viseme is shown
+// by its own frame series. LipSync implements Layer, so it can be composited
+// with the character animation by Scene.
+type LipSync struct {
+	visemeFrameSeries map[string]*FrameSeries
+	restViseme        string
+
+	mutex     sync.Mutex
+	timeline  VisemeTimeline
+	startTime time.Time
+	isPlaying bool
+}
+
+// NewLipSync creates LipSync. visemeFrameSeriesNames maps visemes to frame series names.
+// The restViseme is shown when no speech is played, it may be empty to show nothing.
+func NewLipSync(allFrameSeries []FrameSeries, visemeFrameSeriesNames map[string]string,
+	restViseme string) (*LipSync, error) {
+
+	lipSync := &LipSync{
+		visemeFrameSeries: make(map[string]*FrameSeries),
+		restViseme:        restViseme,
+	}
+
+	frameSeriesIndex := make(map[string]int)
+	for i, frameSeries := range allFrameSeries {
+		frameSeriesIndex[frameSeries.Name] = i
+	}
+
+	for viseme, frameSeriesName := range visemeFrameSeriesNames {
+		i, ok := frameSeriesIndex[frameSeriesName]
+		if !ok {
+			return nil, fmt.Errorf("Could't find a series of frames named '%s'", frameSeriesName)
+		}
+		frameSeries := &allFrameSeries[i]
+
+		if len(frameSeries.Frames) == 0 {
+			return nil, fmt.Errorf("The frame series for viseme '%s' is empty", viseme)
+		}
+
+		lipSync.visemeFrameSeries[viseme] = frameSeries
+	}
+
+	if _, ok := lipSync.visemeFrameSeries[restViseme]; restViseme != "" && !ok {
+		return nil, fmt.Errorf("Could't find a series of frames for viseme '%s'", restViseme)
+	}
+
+	return lipSync, nil
+}
+
+// Play starts playing the timeline. The timeline offsets are counted from startTime,
+// which is usually the time the audio playback starts.
+func (lipSync *LipSync) Play(timeline VisemeTimeline, startTime time.Time) error {
+	for _, key := range timeline {
+		if _, ok := lipSync.visemeFrameSeries[key.Viseme]; !ok {
+			return fmt.Errorf("Could't find a series of frames for viseme '%s'", key.Viseme)
+		}
+	}
+
+	sortedTimeline := append(VisemeTimeline(nil), timeline...)
+	sort.SliceStable(sortedTimeline, func(i, j int) bool {
+		return sortedTimeline[i].Offset < sortedTimeline[j].Offset
+	})
+
+	lipSync.mutex.Lock()
+	lipSync.timeline = sortedTimeline
+	lipSync.startTime = startTime
+	lipSync.isPlaying = true
+	lipSync.mutex.Unlock()
+	return nil
+}
+
+// Stop stops playing the timeline and shows the rest viseme
+func (lipSync *LipSync) Stop() {
+	lipSync.mutex.Lock()
+	lipSync.isPlaying = false
+	lipSync.timeline = nil
+	lipSync.mutex.Unlock()
+}
+
+// Frame gets the mouth frame at the given time.
+// The frames of a viseme series are played at the series frame rate from the viseme start,
+// the last frame is held until the next viseme.
+func (lipSync *LipSync) Frame(now time.Time) *Frame {
+	lipSync.mutex.Lock()
+	defer lipSync.mutex.Unlock()
+
+	viseme := lipSync.restViseme
+	visemeStartTime := now
+	if lipSync.isPlaying && !now.Before(lipSync.startTime) {
+		offset := now.Sub(lipSync.startTime)
+		keyNum := sort.Search(len(lipSync.timeline), func(i int) bool {
+			return lipSync.timeline[i].Offset > offset
+		}) - 1
+		if keyNum >= 0 {
+			viseme = lipSync.timeline[keyNum].Viseme
+			visemeStartTime = lipSync.startTime.Add(lipSync.timeline[keyNum].Offset)
+		}
+	}
+
+	frameSeries, ok := lipSync.visemeFrameSeries[viseme]
+	if !ok {
+		return nil
+	}
+
+	frameRate := frameSeries.FrameRate
+	if frameRate <= 0 {
+		frameRate = defaultFrameRate
+	}
+	frameNum := int(now.Sub(visemeStartTime) * time.Duration(frameRate) / time.Second)
+	if frameNum >= len(frameSeries.Frames) {
+		frameNum = len(frameSeries.Frames) - 1
+	}
+	return &frameSeries.Frames[frameNum]
+}
+
+// ParseWAVVisemeTimeline builds a viseme timeline from the amplitude envelope of PCM WAV data.
+// The envelope is measured in windows of one period of frameRate. The visemes are ordered
+// from the closed mouth to the most open one, louder windows get more open visemes.
+func ParseWAVVisemeTimeline(reader io.Reader, frameRate int, visemes []string) (VisemeTimeline, error) {
+	if frameRate <= 0 {
+		return nil, fmt.Errorf("Invalid frame rate %v", frameRate)
+	}
+
+	if len(visemes) == 0 {
+		return nil, errors.New("Visemes are empty")
+	}
+
+	wav, err := readWAV(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	windowSize := wav.sampleRate / frameRate
+	if windowSize <= 0 {
+		windowSize = 1
+	}
+
+	envelope := make([]float64, 0, len(wav.samples)/windowSize+1)
+	maxLevel := 0.0
+	for start := 0; start < len(wav.samples); start += windowSize {
+		end := start + windowSize
+		if end > len(wav.samples) {
+			end = len(wav.samples)
+		}
+
+		sum := 0.0
+		for _, sample := range wav.samples[start:end] {
+			sum += sample * sample
+		}
+		level := math.Sqrt(sum / float64(end-start))
+		envelope = append(envelope, level)
+		maxLevel = math.Max(maxLevel, level)
+	}
+
+	timeline := VisemeTimeline{}
+	for i, level := range envelope {
+		visemeNum := 0
+		if maxLevel > 0 {
+			visemeNum = int(level / maxLevel * float64(len(visemes)))
+			if visemeNum >= len(visemes) {
+				visemeNum = len(visemes) - 1
+			}
+		}
+
+		viseme := visemes[visemeNum]
+		if len(timeline) > 0 && timeline[len(timeline)-1].Viseme == viseme {
+			continue
+		}
+
+		offset := time.Duration(i*windowSize) * time.Second / time.Duration(wav.sampleRate)
+		timeline = append(timeline, VisemeKey{Offset: offset, Viseme: viseme})
+	}
+
+	// Close the mouth at the end of the speech
+	endOffset := time.Duration(len(wav.samples)) * time.Second / time.Duration(wav.sampleRate)
+	if len(timeline) == 0 || timeline[len(timeline)-1].Viseme != visemes[0] {
+		timeline = append(timeline, VisemeKey{Offset: endOffset, Viseme: visemes[0]})
+	}
+
+	return timeline, nil
+}
+
+// LoadWAVVisemeTimeline loads a viseme timeline from a PCM WAV file (see ParseWAVVisemeTimeline)
+func LoadWAVVisemeTimeline(fileName string, frameRate int, visemes []string) (VisemeTimeline, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseWAVVisemeTimeline(file, frameRate, visemes)
+}
@@ -0,0 +1,95 @@
+package chanim
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLipSyncFrame(t *testing.T) {
+	allFrameSeries := []FrameSeries{makeFrameSeries("r", 1), makeFrameSeries("aa", 2)}
+	lipSync, err := NewLipSync(allFrameSeries, map[string]string{"rest": "r", "aa": "aa"}, "rest")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Unix(0, 0)
+	if err := lipSync.Play(VisemeTimeline{{0, "aa"}, {0, "x"}}, start); err == nil {
+		t.Fatal("Timeline with a missing viseme is played")
+	}
+	if err := lipSync.Play(VisemeTimeline{{100 * time.Millisecond, "rest"}, {0, "aa"}}, start); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		offset time.Duration
+		mark   string
+	}{
+		{-time.Millisecond, "r0"},
+		{0, "aa0"},
+		{40 * time.Millisecond, "aa1"},
+		{80 * time.Millisecond, "aa1"},
+		{100 * time.Millisecond, "r0"},
+	}
+	for _, tt := range tests {
+		frame := lipSync.Frame(start.Add(tt.offset))
+		if mark := frame.DrawOperations[0].(markOperation).name; mark != tt.mark {
+			t.Errorf("Frame at %v: %s, expected %s", tt.offset, mark, tt.mark)
+		}
+	}
+
+	lipSync.Stop()
+	if mark := lipSync.Frame(start).DrawOperations[0].(markOperation).name; mark != "r0" {
+		t.Fatalf("Frame after Stop: %s", mark)
+	}
+}
+
+func TestNewLipSyncErrors(t *testing.T) {
+	allFrameSeries := []FrameSeries{makeFrameSeries("r", 1), {Name: "e"}}
+	tests := []struct {
+		visemeFrameSeriesNames map[string]string
+		restViseme             string
+	}{
+		{map[string]string{"rest": "x"}, "rest"},
+		{map[string]string{"rest": "e"}, "rest"},
+		{map[string]string{"aa": "r"}, "rest"},
+	}
+
+	for _, tt := range tests {
+		if _, err := NewLipSync(allFrameSeries, tt.visemeFrameSeriesNames, tt.restViseme); err == nil {
+			t.Errorf("Visemes %v with rest viseme '%s' are accepted", tt.visemeFrameSeriesNames, tt.restViseme)
+		}
+	}
+}
+
+func TestParseWAVVisemeTimeline(t *testing.T) {
+	var samples []int16
+	for _, level := range []int16{0, 16384, 8192} {
+		for i := 0; i < 800; i++ {
+			samples = append(samples, level)
+		}
+	}
+	wav := makeWAV(fmtChunk(1, 16), dataChunk(samples...))
+
+	timeline, err := ParseWAVVisemeTimeline(bytes.NewReader(wav), 10, []string{"closed", "half", "open"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := VisemeTimeline{
+		{0, "closed"},
+		{100 * time.Millisecond, "open"},
+		{200 * time.Millisecond, "half"},
+		{300 * time.Millisecond, "closed"},
+	}
+	if !reflect.DeepEqual(timeline, expected) {
+		t.Fatalf("Timeline %v, expected %v", timeline, expected)
+	}
+
+	if _, err := ParseWAVVisemeTimeline(bytes.NewReader(wav), 0, []string{"closed"}); err == nil {
+		t.Fatal("Invalid frame rate is accepted")
+	}
+	if _, err := ParseWAVVisemeTimeline(bytes.NewReader(wav), 10, nil); err == nil {
+		t.Fatal("Empty visemes are accepted")
+	}
+}
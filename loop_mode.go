@@ -0,0 +1,34 @@
+package chanim
+
+// LoopMode defines the order in which the animation frames are played
+type LoopMode int
+
+const (
+	// LoopForward plays frames from the first to the last one
+	LoopForward LoopMode = iota
+	// LoopReverse plays frames from the last to the first one
+	LoopReverse
+	// LoopPingPong plays frames forward and then backward without repeating the end frames
+	LoopPingPong
+)
+
+func (mode LoopMode) String() string {
+	switch mode {
+	case LoopForward:
+		return "Forward"
+	case LoopReverse:
+		return "Reverse"
+	case LoopPingPong:
+		return "PingPong"
+	default:
+		return "Unknown"
+	}
+}
+
+// getLoopLength gets the number of frames played in one loop
+func getLoopLength(mode LoopMode, frameCount int) int {
+	if mode == LoopPingPong && frameCount > 1 {
+		return 2*frameCount - 2
+	}
+	return frameCount
+}
@@ -0,0 +1,56 @@
+package chanim
+
+import "sort"
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// naturalLess compares strings treating digit runs as numbers, so "frame2" < "frame10"
+func naturalLess(a string, b string) bool {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if !isDigit(a[i]) || !isDigit(b[j]) {
+			if a[i] != b[j] {
+				return a[i] < b[j]
+			}
+			i++
+			j++
+			continue
+		}
+
+		// Compare digit runs by value ignoring leading zeros
+		iStart, jStart := i, j
+		for i < len(a) && a[i] == '0' {
+			i++
+		}
+		for j < len(b) && b[j] == '0' {
+			j++
+		}
+		iNum, jNum := i, j
+		for i < len(a) && isDigit(a[i]) {
+			i++
+		}
+		for j < len(b) && isDigit(b[j]) {
+			j++
+		}
+
+		if i-iNum != j-jNum {
+			return i-iNum < j-jNum
+		}
+		if a[iNum:i] != b[jNum:j] {
+			return a[iNum:i] < b[jNum:j]
+		}
+		if i-iStart != j-jStart {
+			// Fewer leading zeros first
+			return i-iStart < j-jStart
+		}
+	}
+	return len(a)-i < len(b)-j
+}
+
+func sortNaturally(values []string) {
+	sort.Slice(values, func(i, j int) bool {
+		return naturalLess(values[i], values[j])
+	})
+}
@@ -0,0 +1,48 @@
+package chanim
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNaturalLess(t *testing.T) {
+	tests := []struct {
+		a    string
+		b    string
+		less bool
+	}{
+		{"", "", false},
+		{"", "a", true},
+		{"a", "", false},
+		{"a", "b", true},
+		{"a", "ab", true},
+		{"frame2", "frame10", true},
+		{"frame10", "frame2", false},
+		{"frame10", "frame10", false},
+		{"frame2", "frame02", true},
+		{"frame02", "frame2", false},
+		{"frame007", "frame10", true},
+		{"frame1a", "frame1b", true},
+		{"frame9x", "frame10a", true},
+		{"x10y2", "x10y10", true},
+		{"10", "9", false},
+		{"1", "a", true},
+		{"frame18446744073709551616", "frame18446744073709551617", true},
+	}
+
+	for _, tt := range tests {
+		if less := naturalLess(tt.a, tt.b); less != tt.less {
+			t.Errorf("naturalLess(%q, %q) = %v, expected %v", tt.a, tt.b, less, tt.less)
+		}
+	}
+}
+
+func TestSortNaturally(t *testing.T) {
+	values := []string{"frame10", "frame02", "frame1", "frame2", "frame"}
+	sortNaturally(values)
+
+	expected := []string{"frame", "frame1", "frame2", "frame02", "frame10"}
+	if !reflect.DeepEqual(values, expected) {
+		t.Fatalf("Sorted %v, expected %v", values, expected)
+	}
+}
@@ -16,6 +16,8 @@ type PackedPixmap struct {
 	Width     int
 	Height    int
 	PixFormat PixelFormat
+	// mapping is the whole file mapping if the pixmap is created by MMapPackedPixmap
+	mapping []byte
 }
 
 // Save saves PackedPixmap
@@ -219,6 +221,9 @@ func MMapPackedPixmap(fileName string) (*PackedPixmap, error) {
 		return nil, err
 	}
 	fileSize := int(fileInfo.Size())
+	if fileSize < rawHeaderSize {
+		return nil, errors.New("Invalid header")
+	}
 
 	data, err := syscall.Mmap(int(file.Fd()), 0, fileSize, syscall.PROT_READ, syscall.MAP_PRIVATE)
 	if err != nil {
@@ -227,18 +232,34 @@ func MMapPackedPixmap(fileName string) (*PackedPixmap, error) {
 
 	pp, err := parseHeader(bytes.NewReader(data[0:rawHeaderSize]))
 	if err != nil {
+		syscall.Munmap(data)
 		return nil, err
 	}
 	pp.Data = data[rawHeaderSize:]
+	pp.mapping = data
 
 	err = pp.Check()
 	if err != nil {
+		syscall.Munmap(data)
 		return nil, err
 	}
 
 	return pp, nil
 }
 
+// unmapPackedPixmap unmaps PackedPixmap created by MMapPackedPixmap
+func unmapPackedPixmap(pp *PackedPixmap) error {
+	if pp.mapping == nil {
+		return errors.New("The pixmap is not mapped")
+	}
+	err := syscall.Munmap(pp.mapping)
+	if err == nil {
+		pp.Data = nil
+		pp.mapping = nil
+	}
+	return err
+}
+
 func eqPixels(a []byte, b []byte) bool {
 	return bytes.Equal(a, b)
 }
@@ -0,0 +1,50 @@
+package chanim
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func makeSolidPackedPixmap(width int, height int, color uint16) *PackedPixmap {
+	pp := &PackedPixmap{Width: width, Height: height, PixFormat: RGB16}
+	for y := 0; y < height; y++ {
+		pp.Data = append(pp.Data, byte(width), byte(color), byte(color>>8), 0)
+	}
+	return pp
+}
+
+func TestMMapPackedPixmap(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chanim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fileName := filepath.Join(dir, "frame.ppixmap")
+	expected := makeSolidPackedPixmap(3, 2, 0xf800)
+	if err := expected.Save(fileName); err != nil {
+		t.Fatal(err)
+	}
+
+	pp, err := MMapPackedPixmap(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pp.Width != expected.Width || pp.Height != expected.Height || pp.PixFormat != expected.PixFormat {
+		t.Fatalf("Mapped pixmap %dx%d %v, expected %dx%d %v",
+			pp.Width, pp.Height, pp.PixFormat, expected.Width, expected.Height, expected.PixFormat)
+	}
+	if !reflect.DeepEqual(pp.Data, expected.Data) {
+		t.Fatalf("Mapped data %v, expected %v", pp.Data, expected.Data)
+	}
+
+	if err := unmapPackedPixmap(pp); err != nil {
+		t.Fatalf("Could't unmap the pixmap: %v", err)
+	}
+	if err := unmapPackedPixmap(pp); err == nil {
+		t.Fatal("The pixmap is unmapped twice")
+	}
+}
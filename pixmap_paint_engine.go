@@ -0,0 +1,81 @@
+package chanim
+
+import (
+	"errors"
+	"image"
+)
+
+// pixmapPaintEngine draws into a Pixmap in memory
+type pixmapPaintEngine struct {
+	pixmap *Pixmap
+}
+
+func newPixmapPaintEngine(width int, height int, pixFormat PixelFormat) *pixmapPaintEngine {
+	bytePerLine := width * GetPixelSize(pixFormat)
+	return &pixmapPaintEngine{
+		pixmap: &Pixmap{
+			Data:        make([]byte, bytePerLine*height),
+			Width:       width,
+			Height:      height,
+			BytePerLine: bytePerLine,
+			PixFormat:   pixFormat,
+		},
+	}
+}
+
+func (p *pixmapPaintEngine) GetWidth() int {
+	return p.pixmap.Width
+}
+
+func (p *pixmapPaintEngine) GetHeight() int {
+	return p.pixmap.Height
+}
+
+func (p *pixmapPaintEngine) Begin() error {
+	return nil
+}
+
+func (p *pixmapPaintEngine) bounds() image.Rectangle {
+	return image.Rect(0, 0, p.pixmap.Width, p.pixmap.Height)
+}
+
+func (p *pixmapPaintEngine) Clear(rect image.Rectangle) error {
+	r := rect.Intersect(p.bounds())
+	pixSize := GetPixelSize(p.pixmap.PixFormat)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		row := p.pixmap.Data[y*p.pixmap.BytePerLine:]
+		for i := r.Min.X * pixSize; i < r.Max.X*pixSize; i++ {
+			row[i] = 0
+		}
+	}
+	return nil
+}
+
+func (p *pixmapPaintEngine) DrawPixmap(top image.Point, pixmap *Pixmap) error {
+	if pixmap.PixFormat != p.pixmap.PixFormat {
+		return errors.New("Pixmap has invalid pixel format")
+	}
+
+	rect := image.Rect(top.X, top.Y, top.X+pixmap.Width, top.Y+pixmap.Height)
+	r := rect.Intersect(p.bounds())
+	pixSize := GetPixelSize(pixmap.PixFormat)
+	copySize := r.Dx() * pixSize
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		srcOffset := (y-top.Y)*pixmap.BytePerLine + (r.Min.X-top.X)*pixSize
+		dstOffset := y*p.pixmap.BytePerLine + r.Min.X*pixSize
+		copy(p.pixmap.Data[dstOffset:dstOffset+copySize], pixmap.Data[srcOffset:srcOffset+copySize])
+	}
+	return nil
+}
+
+func (p *pixmapPaintEngine) DrawPackedPixmap(top image.Point, packedPixmap *PackedPixmap) error {
+	pixmap, err := packedPixmap.Unpack()
+	if err != nil {
+		return err
+	}
+	return p.DrawPixmap(top, pixmap)
+}
+
+func (p *pixmapPaintEngine) End() error {
+	return nil
+}
@@ -0,0 +1,283 @@
+package chanim
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type sceneLayer struct {
+	layer Layer
+	z     int
+}
+
+// layerFrame is a frame of a layer to draw
+type layerFrame struct {
+	frame *Frame
+	// animator is nil for layers that aren't animators
+	animator *Animator
+	// isNew is false for the frames of animators that are drawn again
+	isNew        bool
+	drawDuration time.Duration
+	err          error
+}
+
+// Scene composites several layers in z-order into one PaintEngine.
+// Each layer may be an Animator with its own state machine, the layers share
+// the clock of the scene.
+type Scene struct {
+	paintEngine PaintEngine
+	clock       Clock
+
+	mutex     sync.Mutex
+	frameRate int
+	layers    []sceneLayer
+	isRunning bool
+	isClosed  bool
+	drawDone  chan struct{}
+	wake      chan struct{}
+}
+
+// SceneOption configures Scene
+type SceneOption func(scene *Scene)
+
+// WithSceneClock sets the time source of the scene. By default RealClock is used.
+func WithSceneClock(clock Clock) SceneOption {
+	return func(scene *Scene) {
+		scene.clock = clock
+	}
+}
+
+// NewScene creates new Scene
+func NewScene(paintEngine PaintEngine, options ...SceneOption) *Scene {
+	scene := &Scene{
+		paintEngine: paintEngine,
+		clock:       RealClock(),
+		frameRate:   defaultFrameRate,
+	}
+	for _, option := range options {
+		option(scene)
+	}
+	return scene
+}
+
+// SetFrameRate sets the rate at which the scene is redrawn
+func (scene *Scene) SetFrameRate(frameRate int) error {
+	if frameRate <= 0 {
+		return fmt.Errorf("Invalid frame rate %v", frameRate)
+	}
+
+	scene.mutex.Lock()
+	scene.frameRate = frameRate
+	scene.mutex.Unlock()
+	return nil
+}
+
+// AddLayer adds a layer to the scene. Layers with greater z are drawn on top.
+// An Animator must be added before it is started, then Start doesn't create
+// a drawing goroutine and the frames are drawn by the scene.
+// The paint engine of such an animator is not used, NullPaintEngine can be passed to it.
+// The drawing errors and durations are reported to the animator, so its error policy,
+// drop policy, status and stats work as for a standalone animator. The stats count
+// the frames of the animator, a frame held for several scene frames is counted once.
+// An Animator can be added to one scene only once.
+func (scene *Scene) AddLayer(layer Layer, z int) error {
+	if animator, ok := layer.(*Animator); ok {
+		if err := animator.attachToScene(scene); err != nil {
+			return err
+		}
+	}
+
+	scene.mutex.Lock()
+	defer scene.mutex.Unlock()
+
+	scene.layers = append(scene.layers, sceneLayer{layer, z})
+	sort.SliceStable(scene.layers, func(i, j int) bool {
+		return scene.layers[i].z < scene.layers[j].z
+	})
+	return nil
+}
+
+// RemoveLayer removes the layer from the scene.
+// A removed Animator is stopped and can be started standalone.
+func (scene *Scene) RemoveLayer(layer Layer) {
+	scene.mutex.Lock()
+	defer scene.mutex.Unlock()
+
+	for i, sceneLayer := range scene.layers {
+		if sceneLayer.layer == layer {
+			scene.layers = append(scene.layers[:i], scene.layers[i+1:]...)
+			if animator, ok := layer.(*Animator); ok {
+				animator.detachFromScene()
+			}
+			return
+		}
+	}
+}
+
+// Start drawing
+func (scene *Scene) Start() error {
+	scene.mutex.Lock()
+	defer scene.mutex.Unlock()
+
+	if scene.isClosed {
+		return errors.New("Scene is closed")
+	}
+
+	if scene.isRunning {
+		return errors.New("Scene is already running")
+	}
+
+	if scene.drawDone != nil {
+		select {
+		case <-scene.drawDone:
+		default:
+			return errors.New("Scene is still stopping")
+		}
+	}
+
+	for _, sceneLayer := range scene.layers {
+		if animator, ok := sceneLayer.layer.(*Animator); ok {
+			animator.resetLayerSchedule()
+		}
+	}
+
+	scene.isRunning = true
+	scene.drawDone = make(chan struct{})
+	scene.wake = make(chan struct{}, 1)
+	go scene.doDraw(scene.drawDone, scene.wake)
+	return nil
+}
+
+// Stop stops drawing and waits for the drawing goroutine to exit.
+// The layers are not stopped.
+func (scene *Scene) Stop() {
+	scene.mutex.Lock()
+	scene.isRunning = false
+	drawDone := scene.drawDone
+	if scene.wake != nil {
+		select {
+		case scene.wake <- struct{}{}:
+		default:
+		}
+	}
+	scene.mutex.Unlock()
+
+	if drawDone != nil {
+		<-drawDone
+	}
+}
+
+// Close stops drawing and releases the paint engine if it implements io.Closer.
+// The scene can't be started after Close. Calling Close again does nothing.
+func (scene *Scene) Close() error {
+	scene.mutex.Lock()
+	isClosed := scene.isClosed
+	scene.isClosed = true
+	scene.mutex.Unlock()
+
+	if isClosed {
+		return nil
+	}
+
+	scene.Stop()
+
+	if closer, ok := scene.paintEngine.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (scene *Scene) doDraw(drawDone chan struct{}, wake chan struct{}) {
+	defer close(drawDone)
+
+	showNextFrameTime := scene.clock.Now()
+	for {
+		now := scene.clock.Now()
+		frames, frameDuration, ok := scene.getFrames(now)
+		if !ok {
+			break
+		}
+
+		if err := scene.drawFrames(frames); err != nil {
+			logrus.Warnf("Scene: failed to draw frames: %v\n", err)
+		}
+
+		showNextFrameTime = showNextFrameTime.Add(frameDuration)
+		if showNextFrameTime.Before(now) {
+			showNextFrameTime = now
+		}
+		scene.clock.Sleep(showNextFrameTime.Sub(scene.clock.Now()), wake)
+	}
+}
+
+func (scene *Scene) getFrames(now time.Time) ([]layerFrame, time.Duration, bool) {
+	scene.mutex.Lock()
+	defer scene.mutex.Unlock()
+
+	if !scene.isRunning {
+		return nil, 0, false
+	}
+
+	frames := make([]layerFrame, 0, len(scene.layers))
+	for _, sceneLayer := range scene.layers {
+		if animator, ok := sceneLayer.layer.(*Animator); ok {
+			if frame, isNew := animator.layerFrame(now); frame != nil {
+				frames = append(frames, layerFrame{frame: frame, animator: animator, isNew: isNew})
+			}
+			continue
+		}
+
+		if frame := sceneLayer.layer.Frame(now); frame != nil {
+			frames = append(frames, layerFrame{frame: frame})
+		}
+	}
+	return frames, time.Second / time.Duration(scene.frameRate), true
+}
+
+// drawFrames draws the frames of the layers. The errors of a layer don't prevent
+// drawing of the others, the errors and durations are reported to the animators.
+func (scene *Scene) drawFrames(frames []layerFrame) error {
+	paintEngine := scene.paintEngine
+	if err := paintEngine.Begin(); err != nil {
+		return err
+	}
+
+	for i := range frames {
+		layerFrame := &frames[i]
+		drawStartTime := scene.clock.Now()
+		layerFrame.err = layerFrame.frame.Draw(paintEngine)
+		if layerFrame.animator != nil {
+			layerFrame.err = layerFrame.animator.retryLayerFrame(paintEngine, layerFrame.frame, layerFrame.err)
+		}
+		layerFrame.drawDuration = scene.clock.Now().Sub(drawStartTime)
+	}
+
+	presentStartTime := scene.clock.Now()
+	err := paintEngine.End()
+	presentDuration := scene.clock.Now().Sub(presentStartTime)
+
+	for _, layerFrame := range frames {
+		layerErr := layerFrame.err
+		if layerErr == nil {
+			layerErr = err
+		}
+
+		switch {
+		case layerFrame.animator == nil:
+			if layerFrame.err != nil {
+				logrus.Warnf("Scene: failed to draw a layer frame: %v\n", layerFrame.err)
+			}
+		case layerErr != nil:
+			layerFrame.animator.handleDrawError(layerErr)
+		case layerFrame.isNew:
+			layerFrame.animator.addDrawnFrame(layerFrame.drawDuration, presentDuration)
+		}
+	}
+	return err
+}
@@ -0,0 +1,158 @@
+package chanim
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// staticLayer is a layer that isn't an animator
+type staticLayer struct {
+	frame *Frame
+}
+
+func (layer staticLayer) Frame(now time.Time) *Frame {
+	return layer.frame
+}
+
+// sceneTest drives a scene with a manual clock frame by frame
+type sceneTest struct {
+	t           *testing.T
+	scene       *Scene
+	clock       *ManualClock
+	paintEngine *recordingPaintEngine
+}
+
+func newSceneTest(t *testing.T) *sceneTest {
+	test := &sceneTest{
+		t:           t,
+		clock:       NewManualClock(time.Unix(0, 0)),
+		paintEngine: newRecordingPaintEngine(),
+	}
+	test.scene = NewScene(test.paintEngine, WithSceneClock(test.clock))
+	return test
+}
+
+func (test *sceneTest) addAnimator(animations Animations, allFrameSeries []FrameSeries, z int,
+	options ...AnimatorOption) *Animator {
+
+	options = append([]AnimatorOption{WithClock(test.clock)}, options...)
+	animator, err := NewAnimator(NullPaintEngine(), animations, allFrameSeries, options...)
+	if err != nil {
+		test.t.Fatal(err)
+	}
+	if err := test.scene.AddLayer(animator, z); err != nil {
+		test.t.Fatal(err)
+	}
+	if err := animator.Start(animations[0].Name); err != nil {
+		test.t.Fatal(err)
+	}
+	return animator
+}
+
+// step advances the clock by a scene frame until markCount more marks are drawn
+func (test *sceneTest) step(markCount int) {
+	drawnMarkCount := test.paintEngine.frameCount()
+	test.clock.WaitForSleepers(1)
+	test.clock.Advance(time.Second / defaultFrameRate)
+	test.paintEngine.waitForFrames(drawnMarkCount + markCount)
+}
+
+func TestSceneZOrder(t *testing.T) {
+	test := newSceneTest(t)
+	defer test.scene.Close()
+
+	test.addAnimator(Animations{{Name: "A", FrameSeriesName: "a"}}, []FrameSeries{makeFrameSeries("a", 2)}, 2)
+	test.addAnimator(Animations{{Name: "B", FrameSeriesName: "b"}}, []FrameSeries{makeFrameSeries("b", 2)}, 1)
+	static := makeFrameSeries("s", 1)
+	if err := test.scene.AddLayer(staticLayer{&static.Frames[0]}, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := test.scene.Start(); err != nil {
+		t.Fatal(err)
+	}
+	test.paintEngine.waitForFrames(3)
+	test.step(3)
+
+	expected := []string{"s0", "b0", "a0", "s0", "b1", "a1"}
+	if marks := test.paintEngine.waitForFrames(6); !reflect.DeepEqual(marks, expected) {
+		t.Fatalf("Marks %v, expected %v", marks, expected)
+	}
+}
+
+func TestSceneLayerStats(t *testing.T) {
+	test := newSceneTest(t)
+	defer test.scene.Close()
+
+	animator := test.addAnimator(Animations{{Name: "A", FrameSeriesName: "a", FrameRate: 5}},
+		[]FrameSeries{makeFrameSeries("a", 3)}, 0)
+	if err := test.scene.Start(); err != nil {
+		t.Fatal(err)
+	}
+	test.paintEngine.waitForFrames(1)
+	for i := 0; i < 9; i++ {
+		test.step(1)
+	}
+	test.scene.Stop()
+
+	// The held frames are drawn again, but counted once
+	expected := []string{"a0", "a0", "a0", "a0", "a0", "a1", "a1", "a1", "a1", "a1"}
+	if marks := test.paintEngine.waitForFrames(10); !reflect.DeepEqual(marks, expected) {
+		t.Fatalf("Marks %v, expected %v", marks, expected)
+	}
+	if stats := animator.Stats(); stats.FramesDrawn != 2 || stats.FramesDropped != 0 {
+		t.Fatalf("Stats %+v", stats)
+	}
+}
+
+func TestSceneLayerErrors(t *testing.T) {
+	test := newSceneTest(t)
+	defer test.scene.Close()
+
+	a := makeFrameSeries("a", 2)
+	makeFailingFrame(&a.Frames[1], 1)
+	animator := test.addAnimator(Animations{{Name: "A", FrameSeriesName: "a"}}, []FrameSeries{a}, 0,
+		WithErrorPolicy(ErrorPolicy{Action: StopOnError}))
+	if err := test.scene.Start(); err != nil {
+		t.Fatal(err)
+	}
+	test.paintEngine.waitForFrames(1)
+	test.step(1)
+	test.scene.Stop()
+
+	if status := animator.Status(); status.IsRunning || status.ErrorCount != 1 || status.LastError != errTestDraw {
+		t.Fatalf("Status %+v", status)
+	}
+}
+
+func TestSceneLayerMembership(t *testing.T) {
+	test := newSceneTest(t)
+	defer test.scene.Close()
+
+	animations := Animations{{Name: "A", FrameSeriesName: "a"}}
+	allFrameSeries := []FrameSeries{makeFrameSeries("a", 1)}
+	animator := test.addAnimator(animations, allFrameSeries, 0)
+	if err := test.scene.AddLayer(animator, 1); err == nil {
+		t.Fatal("Animator is added to the scene twice")
+	}
+	if err := NewScene(NullPaintEngine()).AddLayer(animator, 0); err == nil {
+		t.Fatal("Animator is added to two scenes")
+	}
+
+	// A removed animator is stopped and can be started standalone
+	test.scene.RemoveLayer(animator)
+	if animator.Status().IsRunning {
+		t.Fatal("Removed animator is running")
+	}
+	if frame := animator.Frame(test.clock.Now()); frame != nil {
+		t.Fatal("Removed animator has a layer frame")
+	}
+	if err := animator.Start("A"); err != nil {
+		t.Fatal(err)
+	}
+	if err := test.scene.AddLayer(animator, 0); err == nil {
+		t.Fatal("Running animator is added to the scene")
+	}
+	animator.Close()
+}
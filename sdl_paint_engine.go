@@ -108,3 +108,22 @@ func (p *sdlPaintEngine) End() error {
 	p.renderer.Present()
 	return nil
 }
+
+// Close destroys the renderer and the window
+func (p *sdlPaintEngine) Close() error {
+	if p.renderer != nil {
+		renderer := p.renderer
+		p.renderer = nil
+		if err := renderer.Destroy(); err != nil {
+			return err
+		}
+	}
+
+	if p.window == nil {
+		return nil
+	}
+
+	window := p.window
+	p.window = nil
+	return window.Destroy()
+}
@@ -0,0 +1,251 @@
+package chanim
+
+import (
+	"errors"
+	"fmt"
+)
+
+// TransitionDiscoveryOptions are options of DiscoverTransitions
+type TransitionDiscoveryOptions struct {
+	// Width and Height are the size of the screen the frames are drawn on
+	Width  int
+	Height int
+	// PixFormat is the pixel format of the frame pixmaps
+	PixFormat PixelFormat
+	// Threshold is the maximum mean difference of pixels in range [0, 1]
+	// for frames to be considered the same
+	Threshold float64
+}
+
+// DiscoveredTransition is a transition added by DiscoverTransitions
+type DiscoveredTransition struct {
+	FrameSeriesName   string
+	FrameNum          int
+	DestAnimationName string
+	// Difference is the mean difference of pixels in range [0, 1]
+	Difference float64
+}
+
+// TransitionWait is the worst-case wait for a transition between animations
+type TransitionWait struct {
+	AnimationPair
+	// Frames is the maximum number of frames shown before the transition starts.
+	// It is -1 if some frame series of the source animation has no transition to the destination animation.
+	Frames int
+}
+
+// TransitionReport is the result of DiscoverTransitions
+type TransitionReport struct {
+	Transitions []DiscoveredTransition
+	Waits       []TransitionWait
+}
+
+// DiscoverTransitions finds the frames of animations that look like the start frames
+// of other animations and adds zero-length transitions to them.
+// The frames are changed in place. The start frames of all variations of the
+// destination animation must match. RandomStart of the destination animation is ignored.
+func DiscoverTransitions(animations Animations, allFrameSeries []FrameSeries,
+	options TransitionDiscoveryOptions) (*TransitionReport, error) {
+
+	if options.Width <= 0 || options.Height <= 0 {
+		return nil, errors.New("Invalid screen size")
+	}
+
+	frameSeriesIndex := make(map[string]int)
+	for i := range allFrameSeries {
+		frameSeriesIndex[allFrameSeries[i].Name] = i
+	}
+
+	canvas := newPixmapPaintEngine(options.Width, options.Height, options.PixFormat)
+	startFrames := make(map[string][]*Pixmap)
+	owners := make(map[string][]string)
+	for i := range animations {
+		animation := &animations[i]
+		for _, frameSeriesName := range animation.GetFrameSeriesNames() {
+			j, ok := frameSeriesIndex[frameSeriesName]
+			if !ok {
+				return nil, fmt.Errorf("Could't find a series of frames named '%s'", frameSeriesName)
+			}
+
+			frames := allFrameSeries[j].Frames
+			if len(frames) == 0 {
+				return nil, fmt.Errorf("The frame series for animation '%s' is empty", animation.Name)
+			}
+
+			startFrameNum := 0
+			if animation.LoopMode == LoopReverse {
+				startFrameNum = len(frames) - 1
+			}
+			startFrame, err := renderFrame(canvas, frames[:startFrameNum+1])
+			if err != nil {
+				return nil, err
+			}
+
+			startFrames[animation.Name] = append(startFrames[animation.Name], startFrame)
+			owners[frameSeriesName] = append(owners[frameSeriesName], animation.Name)
+		}
+	}
+
+	report := &TransitionReport{}
+	for i := range allFrameSeries {
+		frameSeries := &allFrameSeries[i]
+		if len(owners[frameSeries.Name]) == 0 {
+			continue
+		}
+
+		if err := canvas.Clear(canvas.bounds()); err != nil {
+			return nil, err
+		}
+
+		for frameNum := range frameSeries.Frames {
+			frame := &frameSeries.Frames[frameNum]
+			if err := frame.Draw(canvas); err != nil {
+				return nil, err
+			}
+
+			for _, destAnimation := range animations {
+				if containsString(owners[frameSeries.Name], destAnimation.Name) {
+					continue
+				}
+				if _, ok := frame.GetSeriesForTransition(destAnimation.Name); ok {
+					continue
+				}
+
+				difference := 0.0
+				for _, startFrame := range startFrames[destAnimation.Name] {
+					d := pixelDifference(canvas.pixmap, startFrame)
+					if d > difference {
+						difference = d
+					}
+				}
+				if difference > options.Threshold {
+					continue
+				}
+
+				frame.Transitions = append(frame.Transitions, Transition{DestAnimationName: destAnimation.Name})
+				report.Transitions = append(report.Transitions, DiscoveredTransition{
+					FrameSeriesName:   frameSeries.Name,
+					FrameNum:          frameNum,
+					DestAnimationName: destAnimation.Name,
+					Difference:        difference,
+				})
+			}
+		}
+	}
+
+	for i := range animations {
+		for j := range animations {
+			if i == j {
+				continue
+			}
+
+			wait := TransitionWait{AnimationPair: AnimationPair{From: animations[i].Name, To: animations[j].Name}}
+			if _, ok := animations[i].GetSeriesForTransition(animations[j].Name); ok {
+				report.Waits = append(report.Waits, wait)
+				continue
+			}
+
+			for _, frameSeriesName := range animations[i].GetFrameSeriesNames() {
+				frames := allFrameSeries[frameSeriesIndex[frameSeriesName]].Frames
+				frameWait := getTransitionWait(frames, animations[i].LoopMode, animations[j].Name)
+				if frameWait < 0 {
+					wait.Frames = -1
+					break
+				}
+				if frameWait > wait.Frames {
+					wait.Frames = frameWait
+				}
+			}
+			report.Waits = append(report.Waits, wait)
+		}
+	}
+
+	return report, nil
+}
+
+// renderFrame draws frames from a clear screen and returns a copy of the result
+func renderFrame(canvas *pixmapPaintEngine, frames []Frame) (*Pixmap, error) {
+	if err := canvas.Clear(canvas.bounds()); err != nil {
+		return nil, err
+	}
+
+	for i := range frames {
+		if err := frames[i].Draw(canvas); err != nil {
+			return nil, err
+		}
+	}
+
+	pixmap := *canvas.pixmap
+	pixmap.Data = append([]byte(nil), canvas.pixmap.Data...)
+	return &pixmap, nil
+}
+
+// pixelDifference gets the mean difference of pixels of equally sized pixmaps in range [0, 1]
+func pixelDifference(a *Pixmap, b *Pixmap) float64 {
+	var sum float64
+	pixSize := GetPixelSize(a.PixFormat)
+	for y := 0; y < a.Height; y++ {
+		for x := 0; x < a.Width; x++ {
+			ar, ag, ab := getPixelColor(a, a.Data[y*a.BytePerLine+x*pixSize:])
+			br, bg, bb := getPixelColor(b, b.Data[y*b.BytePerLine+x*pixSize:])
+			sum += absDiff(ar, br) + absDiff(ag, bg) + absDiff(ab, bb)
+		}
+	}
+	return sum / float64(3*a.Width*a.Height)
+}
+
+// getPixelColor gets the color components of a pixel in range [0, 1]
+func getPixelColor(pixmap *Pixmap, pixel []byte) (float64, float64, float64) {
+	switch pixmap.PixFormat {
+	case RGB16:
+		v := uint16(pixel[0]) | uint16(pixel[1])<<8
+		return float64(v>>11) / 31, float64((v>>5)&0x3f) / 63, float64(v&0x1f) / 31
+	default:
+		return float64(pixel[2]) / 255, float64(pixel[1]) / 255, float64(pixel[0]) / 255
+	}
+}
+
+func absDiff(a float64, b float64) float64 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// getTransitionWait gets the maximum number of frames shown before a frame
+// with the transition to the destination animation, or -1 if there is no such frame
+func getTransitionWait(frames []Frame, loopMode LoopMode, destAnimationName string) int {
+	loopLength := getLoopLength(loopMode, len(frames))
+	isTransition := make([]bool, loopLength)
+	hasTransition := false
+	for i := 0; i < loopLength; i++ {
+		frameNum := i
+		switch {
+		case loopMode == LoopReverse:
+			frameNum = len(frames) - 1 - i
+		case i >= len(frames):
+			frameNum = loopLength - i
+		}
+
+		if _, ok := frames[frameNum].GetSeriesForTransition(destAnimationName); ok {
+			isTransition[i] = true
+			hasTransition = true
+		}
+	}
+
+	if !hasTransition {
+		return -1
+	}
+
+	wait := 0
+	for i := range isTransition {
+		frameWait := 0
+		for !isTransition[(i+frameWait)%loopLength] {
+			frameWait++
+		}
+		if frameWait > wait {
+			wait = frameWait
+		}
+	}
+	return wait
+}
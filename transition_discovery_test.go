@@ -0,0 +1,112 @@
+package chanim
+
+import (
+	"image"
+	"reflect"
+	"testing"
+)
+
+func makeTransitionFrames(frameCount int, destAnimationName string, transitionFrameNums ...int) []Frame {
+	frames := make([]Frame, frameCount)
+	for _, i := range transitionFrameNums {
+		frames[i].Transitions = []Transition{{DestAnimationName: destAnimationName, FrameSeriesName: "t"}}
+	}
+	return frames
+}
+
+func TestGetTransitionWait(t *testing.T) {
+	tests := []struct {
+		name     string
+		frames   []Frame
+		loopMode LoopMode
+		wait     int
+	}{
+		{"forward first", makeTransitionFrames(4, "b", 0), LoopForward, 3},
+		{"forward every other", makeTransitionFrames(4, "b", 1, 3), LoopForward, 1},
+		{"forward all", makeTransitionFrames(3, "b", 0, 1, 2), LoopForward, 0},
+		{"forward other destination", makeTransitionFrames(3, "c", 0), LoopForward, -1},
+		{"forward none", makeTransitionFrames(3, "b"), LoopForward, -1},
+		{"reverse", makeTransitionFrames(5, "b", 3, 4), LoopReverse, 3},
+		{"reverse none", makeTransitionFrames(3, "b"), LoopReverse, -1},
+		{"ping-pong last", makeTransitionFrames(4, "b", 3), LoopPingPong, 5},
+		{"ping-pong middle", makeTransitionFrames(4, "b", 1), LoopPingPong, 3},
+		{"ping-pong first", makeTransitionFrames(4, "b", 0), LoopPingPong, 5},
+		{"ping-pong single frame", makeTransitionFrames(1, "b", 0), LoopPingPong, 0},
+		{"ping-pong none", makeTransitionFrames(4, "b"), LoopPingPong, -1},
+	}
+
+	for _, tt := range tests {
+		if wait := getTransitionWait(tt.frames, tt.loopMode, "b"); wait != tt.wait {
+			t.Errorf("%s: wait %v, expected %v", tt.name, wait, tt.wait)
+		}
+	}
+}
+
+func makeSolidFrameSeries(name string, colors ...uint16) FrameSeries {
+	frameSeries := FrameSeries{Name: name}
+	for _, color := range colors {
+		frameSeries.Frames = append(frameSeries.Frames, Frame{
+			DrawOperations: []DrawOperation{
+				NewDrawPackedPixmapOperation(image.Point{}, makeSolidPackedPixmap(1, 1, color)),
+			},
+		})
+	}
+	return frameSeries
+}
+
+func TestDiscoverTransitions(t *testing.T) {
+	animations := Animations{
+		{Name: "a", FrameSeriesName: "a"},
+		{Name: "b", FrameSeriesName: "b"},
+	}
+	allFrameSeries := []FrameSeries{
+		makeSolidFrameSeries("a", 0xf800, 0x07e0, 0x001f),
+		makeSolidFrameSeries("b", 0x07e0, 0xffff),
+	}
+	options := TransitionDiscoveryOptions{Width: 1, Height: 1, PixFormat: RGB16, Threshold: 0.01}
+
+	report, err := DiscoverTransitions(animations, allFrameSeries, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedTransitions := []DiscoveredTransition{{FrameSeriesName: "a", FrameNum: 1, DestAnimationName: "b"}}
+	if !reflect.DeepEqual(report.Transitions, expectedTransitions) {
+		t.Errorf("Transitions %+v, expected %+v", report.Transitions, expectedTransitions)
+	}
+	expectedWaits := []TransitionWait{
+		{AnimationPair{From: "a", To: "b"}, 2},
+		{AnimationPair{From: "b", To: "a"}, -1},
+	}
+	if !reflect.DeepEqual(report.Waits, expectedWaits) {
+		t.Errorf("Waits %+v, expected %+v", report.Waits, expectedWaits)
+	}
+	if _, ok := allFrameSeries[0].Frames[1].GetSeriesForTransition("b"); !ok {
+		t.Error("The transition is not added to the frame")
+	}
+
+	// The discovered transition is not added twice
+	report, err = DiscoverTransitions(animations, allFrameSeries, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Transitions) != 0 {
+		t.Errorf("Transitions are discovered again: %+v", report.Transitions)
+	}
+}
+
+func TestDiscoverTransitionsErrors(t *testing.T) {
+	animations := Animations{{Name: "a", FrameSeriesName: "a"}}
+	options := TransitionDiscoveryOptions{Width: 1, Height: 1, PixFormat: RGB16}
+
+	if _, err := DiscoverTransitions(animations, []FrameSeries{makeSolidFrameSeries("a", 0)},
+		TransitionDiscoveryOptions{PixFormat: RGB16}); err == nil {
+		t.Error("Invalid screen size is accepted")
+	}
+	if _, err := DiscoverTransitions(animations, nil, options); err == nil {
+		t.Error("Missing frame series is accepted")
+	}
+	if _, err := DiscoverTransitions(animations, []FrameSeries{{Name: "a"}}, options); err == nil {
+		t.Error("Empty frame series is accepted")
+	}
+}
@@ -0,0 +1,231 @@
+package chanim
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidationProblemKind is the kind of a problem found by Validate
+type ValidationProblemKind int
+
+const (
+	// MissingFrameSeries is a reference to a frame series that doesn't exist
+	MissingFrameSeries ValidationProblemKind = iota
+	// EmptyFrameSeries is a frame series without frames played by an animation
+	EmptyFrameSeries
+	// DanglingTransition is a reference to an animation that doesn't exist
+	DanglingTransition
+	// DuplicateName is a name used by several animations or frame series
+	DuplicateName
+	// UnreachableAnimation is an animation that can't be reached from any other animation
+	// by a transition. It is a warning, since such an animation may be entered by
+	// Animator.Start, Animator.ChangeAnimationNow or the fallback of ErrorPolicy.
+	UnreachableAnimation
+	// EmptyTransitionSeries is a frame series without frames that isn't played by any animation.
+	// It is a warning, since empty transition and interrupt series are played as zero-length transitions.
+	EmptyTransitionSeries
+)
+
+// IsWarning checks whether the problem doesn't prevent the animations from being played
+func (kind ValidationProblemKind) IsWarning() bool {
+	return kind == UnreachableAnimation || kind == EmptyTransitionSeries
+}
+
+func (kind ValidationProblemKind) String() string {
+	switch kind {
+	case MissingFrameSeries:
+		return "MissingFrameSeries"
+	case EmptyFrameSeries:
+		return "EmptyFrameSeries"
+	case DanglingTransition:
+		return "DanglingTransition"
+	case DuplicateName:
+		return "DuplicateName"
+	case UnreachableAnimation:
+		return "UnreachableAnimation"
+	case EmptyTransitionSeries:
+		return "EmptyTransitionSeries"
+	default:
+		return "Unknown"
+	}
+}
+
+// ValidationProblem is a problem found by Validate
+type ValidationProblem struct {
+	Kind ValidationProblemKind
+	// Name is the name of the animation or the frame series that has the problem
+	Name    string
+	Message string
+}
+
+func (problem ValidationProblem) String() string {
+	return fmt.Sprintf("%v: %s", problem.Kind, problem.Message)
+}
+
+// ValidationError contains all problems found by Validate
+type ValidationError struct {
+	Problems []ValidationProblem
+}
+
+func (err *ValidationError) hasErrors() bool {
+	for _, problem := range err.Problems {
+		if !problem.Kind.IsWarning() {
+			return true
+		}
+	}
+	return false
+}
+
+func (err *ValidationError) Error() string {
+	messages := make([]string, 0, len(err.Problems))
+	for _, problem := range err.Problems {
+		messages = append(messages, problem.String())
+	}
+	return fmt.Sprintf("Invalid animations (%v problems): %s", len(err.Problems), strings.Join(messages, "; "))
+}
+
+type validator struct {
+	animations     map[string]*Animation
+	allFrameSeries map[string]*FrameSeries
+	problems       []ValidationProblem
+}
+
+func (v *validator) report(kind ValidationProblemKind, name string, format string, args ...interface{}) {
+	v.problems = append(v.problems, ValidationProblem{
+		Kind:    kind,
+		Name:    name,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *validator) checkFrameSeriesRef(ownerName string, frameSeriesName string) {
+	if _, ok := v.allFrameSeries[frameSeriesName]; !ok {
+		v.report(MissingFrameSeries, ownerName,
+			"'%s' refers to a missing series of frames named '%s'", ownerName, frameSeriesName)
+	}
+}
+
+func (v *validator) checkAnimationRef(ownerName string, animationName string) {
+	if _, ok := v.animations[animationName]; !ok {
+		v.report(DanglingTransition, ownerName,
+			"'%s' refers to a missing animation named '%s'", ownerName, animationName)
+	}
+}
+
+// Validate checks animations and frame series for missing and empty series,
+// dangling transitions, duplicate names and unreachable animations.
+// It returns *ValidationError with all found problems, including warnings, or nil.
+func Validate(animations Animations, allFrameSeries []FrameSeries) error {
+	v := &validator{
+		animations:     make(map[string]*Animation),
+		allFrameSeries: make(map[string]*FrameSeries),
+	}
+
+	for i := range animations {
+		animation := &animations[i]
+		if _, ok := v.animations[animation.Name]; ok {
+			v.report(DuplicateName, animation.Name, "Duplicate animation name '%s'", animation.Name)
+			continue
+		}
+		v.animations[animation.Name] = animation
+	}
+
+	playedFrameSeriesNames := make(map[string]bool)
+	for i := range animations {
+		for _, frameSeriesName := range animations[i].GetFrameSeriesNames() {
+			playedFrameSeriesNames[frameSeriesName] = true
+		}
+	}
+
+	for i := range allFrameSeries {
+		frameSeries := &allFrameSeries[i]
+		if _, ok := v.allFrameSeries[frameSeries.Name]; ok {
+			v.report(DuplicateName, frameSeries.Name, "Duplicate frame series name '%s'", frameSeries.Name)
+			continue
+		}
+		v.allFrameSeries[frameSeries.Name] = frameSeries
+
+		if len(frameSeries.Frames) == 0 {
+			kind := EmptyTransitionSeries
+			if playedFrameSeriesNames[frameSeries.Name] {
+				kind = EmptyFrameSeries
+			}
+			v.report(kind, frameSeries.Name, "The frame series '%s' is empty", frameSeries.Name)
+		}
+
+		for _, frame := range frameSeries.Frames {
+			for _, transition := range frame.Transitions {
+				v.checkAnimationRef(frameSeries.Name, transition.DestAnimationName)
+			}
+		}
+	}
+
+	for _, frameSeries := range allFrameSeries {
+		for _, frame := range frameSeries.Frames {
+			for _, transition := range frame.Transitions {
+				if transition.FrameSeriesName != "" {
+					v.checkFrameSeriesRef(frameSeries.Name, transition.FrameSeriesName)
+				}
+			}
+		}
+	}
+
+	for _, animation := range animations {
+		for _, frameSeriesName := range animation.GetFrameSeriesNames() {
+			v.checkFrameSeriesRef(animation.Name, frameSeriesName)
+		}
+		if animation.InterruptFrameSeriesName != "" {
+			v.checkFrameSeriesRef(animation.Name, animation.InterruptFrameSeriesName)
+		}
+		if animation.NextAnimationName != "" {
+			v.checkAnimationRef(animation.Name, animation.NextAnimationName)
+		}
+		for _, transition := range animation.Transitions {
+			v.checkAnimationRef(animation.Name, transition.DestAnimationName)
+			if transition.FrameSeriesName != "" {
+				v.checkFrameSeriesRef(animation.Name, transition.FrameSeriesName)
+			}
+		}
+	}
+
+	v.checkReachability(animations, allFrameSeries)
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+func (v *validator) checkReachability(animations Animations, allFrameSeries []FrameSeries) {
+	if len(v.animations) < 2 {
+		return
+	}
+
+	reachable := make(map[string]bool)
+	graph := newAnimationGraph(animations, allFrameSeries)
+	for fromAnimationName, edges := range graph.edges {
+		for _, edge := range edges {
+			if edge.destAnimationName != fromAnimationName {
+				reachable[edge.destAnimationName] = true
+			}
+		}
+	}
+	for _, animation := range animations {
+		if animation.NextAnimationName != "" && animation.NextAnimationName != animation.Name {
+			reachable[animation.NextAnimationName] = true
+		}
+	}
+
+	for i := range animations {
+		animation := &animations[i]
+		if v.animations[animation.Name] != animation {
+			// Duplicates are already reported
+			continue
+		}
+
+		if !reachable[animation.Name] {
+			v.report(UnreachableAnimation, animation.Name,
+				"The animation '%s' can't be reached from any other animation", animation.Name)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package chanim
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	a := makeFrameSeries("a", 2)
+	a.Frames[0].Transitions = []Transition{{DestAnimationName: "B"}}
+	b := makeFrameSeries("b", 1)
+	b.Frames[0].Transitions = []Transition{{DestAnimationName: "A"}}
+	valid := Animations{
+		{Name: "A", FrameSeriesName: "a"},
+		{Name: "B", FrameSeriesName: "b"},
+	}
+
+	tests := []struct {
+		name           string
+		animations     Animations
+		allFrameSeries []FrameSeries
+		kinds          []ValidationProblemKind
+	}{
+		{
+			name:           "valid",
+			animations:     valid,
+			allFrameSeries: []FrameSeries{a, b},
+		},
+		{
+			name: "missing frame series",
+			animations: Animations{
+				{Name: "A", FrameSeriesName: "a", InterruptFrameSeriesName: "x"},
+				{Name: "B", FrameSeriesName: "y", Transitions: []Transition{{DestAnimationName: "A", FrameSeriesName: "z"}}},
+			},
+			allFrameSeries: []FrameSeries{a},
+			kinds: []ValidationProblemKind{
+				MissingFrameSeries, MissingFrameSeries, MissingFrameSeries,
+				// The transition with the missing series doesn't make 'A' reachable
+				UnreachableAnimation,
+			},
+		},
+		{
+			name: "empty animation series",
+			animations: Animations{
+				{Name: "A", FrameSeriesName: "a"},
+				{Name: "B", Variations: []Variation{{FrameSeriesName: "b", Weight: 1}, {FrameSeriesName: "e", Weight: 1}}},
+			},
+			allFrameSeries: []FrameSeries{a, b, {Name: "e"}},
+			kinds:          []ValidationProblemKind{EmptyFrameSeries},
+		},
+		{
+			name: "empty transition series",
+			animations: Animations{
+				{Name: "A", FrameSeriesName: "a", Transitions: []Transition{{DestAnimationName: "B", FrameSeriesName: "ab"}}},
+				{Name: "B", FrameSeriesName: "b", InterruptFrameSeriesName: "i"},
+			},
+			allFrameSeries: []FrameSeries{a, b, {Name: "ab"}, {Name: "i"}},
+			kinds:          []ValidationProblemKind{EmptyTransitionSeries, EmptyTransitionSeries},
+		},
+		{
+			name: "dangling transitions",
+			animations: Animations{
+				{Name: "A", FrameSeriesName: "a", NextAnimationName: "X"},
+				{Name: "B", FrameSeriesName: "b", Transitions: []Transition{{DestAnimationName: "Y"}}},
+			},
+			allFrameSeries: []FrameSeries{a, b, {Name: "c", Frames: []Frame{{Transitions: []Transition{{DestAnimationName: "Z"}}}}}},
+			kinds:          []ValidationProblemKind{DanglingTransition, DanglingTransition, DanglingTransition},
+		},
+		{
+			name:           "duplicate names",
+			animations:     append(valid, Animation{Name: "A", FrameSeriesName: "b"}),
+			allFrameSeries: []FrameSeries{a, b, b},
+			kinds:          []ValidationProblemKind{DuplicateName, DuplicateName},
+		},
+		{
+			name:           "unreachable animation",
+			animations:     append(valid, Animation{Name: "C", FrameSeriesName: "b"}),
+			allFrameSeries: []FrameSeries{a, b},
+			kinds:          []ValidationProblemKind{UnreachableAnimation},
+		},
+	}
+
+	for _, tt := range tests {
+		err := Validate(tt.animations, tt.allFrameSeries)
+		var kinds []ValidationProblemKind
+		if err != nil {
+			validationErr, ok := err.(*ValidationError)
+			if !ok {
+				t.Fatalf("%s: unexpected error %v", tt.name, err)
+			}
+			for _, problem := range validationErr.Problems {
+				kinds = append(kinds, problem.Kind)
+			}
+		}
+		if !reflect.DeepEqual(kinds, tt.kinds) {
+			t.Errorf("%s: problems %v, expected %v", tt.name, err, tt.kinds)
+		}
+
+		hasErrors := false
+		for _, kind := range tt.kinds {
+			hasErrors = hasErrors || !kind.IsWarning()
+		}
+		if _, err := NewAnimator(NullPaintEngine(), tt.animations, tt.allFrameSeries); (err != nil) != hasErrors {
+			t.Errorf("%s: NewAnimator error %v", tt.name, err)
+		}
+	}
+}
+
+func TestEmptyTransitionSeries(t *testing.T) {
+	a := makeFrameSeries("a", 2)
+	a.Frames[1].Transitions = []Transition{{DestAnimationName: "B", FrameSeriesName: "ab"}}
+	animations := Animations{
+		{Name: "A", FrameSeriesName: "a"},
+		{Name: "B", FrameSeriesName: "b"},
+	}
+	test := startAnimatorTest(t, animations, []FrameSeries{a, makeFrameSeries("b", 1), {Name: "ab"}}, "A")
+	defer test.close()
+
+	change := test.animator.ChangeAnimationAsync(context.Background(), "B")
+	test.step(2)
+	if err := changeResult(t, change); err != nil {
+		t.Fatal(err)
+	}
+	test.checkFrames("a0", "a1", "b0")
+	test.checkAnimation("B")
+}
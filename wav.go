@@ -0,0 +1,122 @@
+package chanim
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+)
+
+// wavSamples contains PCM samples of a WAV file normalized to [-1, 1].
+// The samples of all channels are mixed down to mono.
+type wavSamples struct {
+	sampleRate int
+	samples    []float64
+}
+
+type wavFormat struct {
+	AudioFormat   uint16
+	Channels      uint16
+	SampleRate    uint32
+	ByteRate      uint32
+	BlockAlign    uint16
+	BitsPerSample uint16
+}
+
+const wavPCMFormat = 1
+
+func readWAV(reader io.Reader) (*wavSamples, error) {
+	var riffHeader [12]byte
+	if _, err := io.ReadFull(reader, riffHeader[:]); err != nil {
+		return nil, err
+	}
+	if string(riffHeader[0:4]) != "RIFF" || string(riffHeader[8:12]) != "WAVE" {
+		return nil, errors.New("Invalid WAV header")
+	}
+
+	var format *wavFormat
+	for {
+		var chunkHeader [8]byte
+		if _, err := io.ReadFull(reader, chunkHeader[:]); err != nil {
+			if err == io.EOF {
+				return nil, errors.New("WAV data chunk is not found")
+			}
+			return nil, err
+		}
+		chunkID := string(chunkHeader[0:4])
+		chunkSize := int64(binary.LittleEndian.Uint32(chunkHeader[4:8]))
+
+		switch chunkID {
+		case "fmt ":
+			if chunkSize < 16 {
+				return nil, errors.New("Invalid WAV format chunk")
+			}
+			format = &wavFormat{}
+			if err := binary.Read(reader, binary.LittleEndian, format); err != nil {
+				return nil, err
+			}
+			if _, err := io.CopyN(ioutil.Discard, reader, chunkSize-16+chunkSize%2); err != nil {
+				return nil, err
+			}
+		case "data":
+			if format == nil {
+				return nil, errors.New("WAV format chunk is not found")
+			}
+			// Streaming writers don't know the data size and write the maximum,
+			// so the data is read up to the chunk size or to the end of the file
+			data, err := ioutil.ReadAll(io.LimitReader(reader, chunkSize))
+			if err != nil {
+				return nil, err
+			}
+			return decodeWAVSamples(format, data)
+		default:
+			if _, err := io.CopyN(ioutil.Discard, reader, chunkSize+chunkSize%2); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func decodeWAVSamples(format *wavFormat, data []byte) (*wavSamples, error) {
+	if format.AudioFormat != wavPCMFormat {
+		return nil, errors.New("Unsupported WAV audio format")
+	}
+	if format.Channels == 0 {
+		return nil, errors.New("Invalid WAV channel count")
+	}
+
+	if format.SampleRate == 0 {
+		return nil, errors.New("Invalid WAV sample rate")
+	}
+
+	if format.BitsPerSample != 8 && format.BitsPerSample != 16 {
+		return nil, errors.New("Unsupported WAV sample size")
+	}
+	sampleSize := int(format.BitsPerSample / 8)
+
+	channels := int(format.Channels)
+	frameSize := sampleSize * channels
+	if int(format.BlockAlign) != frameSize {
+		return nil, errors.New("Invalid WAV block align")
+	}
+	frameCount := len(data) / frameSize
+	samples := make([]float64, frameCount)
+	for i := 0; i < frameCount; i++ {
+		sum := 0.0
+		for ch := 0; ch < channels; ch++ {
+			offset := i*frameSize + ch*sampleSize
+			if sampleSize == 1 {
+				// 8-bit samples are unsigned
+				sum += (float64(data[offset]) - 128) / 128
+			} else {
+				sum += float64(int16(binary.LittleEndian.Uint16(data[offset:]))) / 32768
+			}
+		}
+		samples[i] = sum / float64(channels)
+	}
+
+	return &wavSamples{
+		sampleRate: int(format.SampleRate),
+		samples:    samples,
+	}, nil
+}
@@ -0,0 +1,183 @@
+package chanim
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+type wavChunk struct {
+	id   string
+	size uint32
+	data []byte
+}
+
+// makeWAV makes a WAV file from the chunks padding them to even sizes
+func makeWAV(chunks ...wavChunk) []byte {
+	var body bytes.Buffer
+	body.WriteString("WAVE")
+	for _, chunk := range chunks {
+		body.WriteString(chunk.id)
+		binary.Write(&body, binary.LittleEndian, chunk.size)
+		body.Write(chunk.data)
+		if len(chunk.data)%2 != 0 {
+			body.WriteByte(0)
+		}
+	}
+
+	var wav bytes.Buffer
+	wav.WriteString("RIFF")
+	binary.Write(&wav, binary.LittleEndian, uint32(body.Len()))
+	wav.Write(body.Bytes())
+	return wav.Bytes()
+}
+
+func fmtChunk(channels int, bitsPerSample int) wavChunk {
+	blockAlign := channels * bitsPerSample / 8
+	return makeFmtChunk(wavFormat{
+		AudioFormat:   wavPCMFormat,
+		Channels:      uint16(channels),
+		SampleRate:    8000,
+		ByteRate:      uint32(8000 * blockAlign),
+		BlockAlign:    uint16(blockAlign),
+		BitsPerSample: uint16(bitsPerSample),
+	})
+}
+
+func makeFmtChunk(format wavFormat) wavChunk {
+	var data bytes.Buffer
+	binary.Write(&data, binary.LittleEndian, format)
+	return wavChunk{id: "fmt ", size: uint32(data.Len()), data: data.Bytes()}
+}
+
+func dataChunk(samples ...int16) wavChunk {
+	var data bytes.Buffer
+	binary.Write(&data, binary.LittleEndian, samples)
+	return wavChunk{id: "data", size: uint32(data.Len()), data: data.Bytes()}
+}
+
+func TestReadWAV(t *testing.T) {
+	nonPCMFormat := fmtChunk(1, 16)
+	nonPCMFormat.data[0] = 3
+	zeroRateFormat := fmtChunk(1, 16)
+	zeroRateFormat.data[4] = 0
+	zeroRateFormat.data[5] = 0
+
+	tests := []struct {
+		name    string
+		wav     []byte
+		samples []float64
+		isError bool
+	}{
+		{
+			name:    "16-bit mono",
+			wav:     makeWAV(fmtChunk(1, 16), dataChunk(0, 16384, -32768)),
+			samples: []float64{0, 0.5, -1},
+		},
+		{
+			name:    "8-bit mono",
+			wav:     makeWAV(fmtChunk(1, 8), wavChunk{id: "data", size: 3, data: []byte{128, 192, 0}}),
+			samples: []float64{0, 0.5, -1},
+		},
+		{
+			name:    "16-bit stereo",
+			wav:     makeWAV(fmtChunk(2, 16), dataChunk(16384, -16384, 16384, 16384)),
+			samples: []float64{0, 0.5},
+		},
+		{
+			name: "unknown chunk with padding",
+			wav: makeWAV(wavChunk{id: "LIST", size: 3, data: []byte{1, 2, 3}},
+				fmtChunk(1, 16), dataChunk(16384)),
+			samples: []float64{0.5},
+		},
+		{
+			name:    "streaming data size",
+			wav:     makeWAV(fmtChunk(1, 16), wavChunk{id: "data", size: 0xffffffff, data: []byte{0, 0x40, 0, 0xc0}}),
+			samples: []float64{0.5, -0.5},
+		},
+		{
+			name:    "incomplete sample frame",
+			wav:     makeWAV(fmtChunk(2, 16), wavChunk{id: "data", size: 6, data: []byte{0, 0x40, 0, 0x40, 0, 0}}),
+			samples: []float64{0.5},
+		},
+		{
+			name:    "not RIFF",
+			wav:     append([]byte("RIFX"), makeWAV(fmtChunk(1, 16), dataChunk(0))[4:]...),
+			isError: true,
+		},
+		{
+			name:    "truncated header",
+			wav:     []byte("RIFF"),
+			isError: true,
+		},
+		{
+			name:    "no data chunk",
+			wav:     makeWAV(fmtChunk(1, 16)),
+			isError: true,
+		},
+		{
+			name:    "data before format",
+			wav:     makeWAV(dataChunk(0), fmtChunk(1, 16)),
+			isError: true,
+		},
+		{
+			name:    "short format chunk",
+			wav:     makeWAV(wavChunk{id: "fmt ", size: 8, data: make([]byte, 8)}, dataChunk(0)),
+			isError: true,
+		},
+		{
+			name:    "non-PCM format",
+			wav:     makeWAV(nonPCMFormat, dataChunk(0)),
+			isError: true,
+		},
+		{
+			name:    "zero channels",
+			wav:     makeWAV(makeFmtChunk(wavFormat{AudioFormat: wavPCMFormat, SampleRate: 8000, BitsPerSample: 16}), dataChunk(0)),
+			isError: true,
+		},
+		{
+			name:    "zero sample rate",
+			wav:     makeWAV(zeroRateFormat, dataChunk(0)),
+			isError: true,
+		},
+		{
+			name:    "24-bit samples",
+			wav:     makeWAV(fmtChunk(1, 24), dataChunk(0, 0, 0)),
+			isError: true,
+		},
+		{
+			name: "invalid block align",
+			wav: makeWAV(makeFmtChunk(wavFormat{
+				AudioFormat: wavPCMFormat, Channels: 2, SampleRate: 8000, BlockAlign: 2, BitsPerSample: 16,
+			}), dataChunk(0)),
+			isError: true,
+		},
+		{
+			name:    "truncated chunk",
+			wav:     makeWAV(fmtChunk(1, 16), wavChunk{id: "LIST", size: 100, data: []byte{1, 2}}),
+			isError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		wav, err := readWAV(bytes.NewReader(tt.wav))
+		if tt.isError {
+			if err == nil {
+				t.Errorf("%s: no error", tt.name)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if wav.sampleRate != 8000 {
+			t.Errorf("%s: sample rate %v", tt.name, wav.sampleRate)
+		}
+		if !reflect.DeepEqual(wav.samples, tt.samples) {
+			t.Errorf("%s: samples %v, expected %v", tt.name, wav.samples, tt.samples)
+		}
+	}
+}